@@ -0,0 +1,265 @@
+package gocbcoreps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+
+	"github.com/couchbase/goprotostellar/genproto/analytics_v1"
+)
+
+type AnalyticsOptions struct {
+	ScanConsistency *analytics_v1.AnalyticsQueryRequest_ScanConsistency
+	ReadOnly        bool
+	ClientContextID string
+
+	// Priority causes the query to be prioritised over others by the analytics service.
+	Priority bool
+
+	// NamedParameters are JSON encoded, names without a leading $ have one added.
+	NamedParameters map[string]any
+
+	// PositionalParameters are JSON encoded and bound to $1, $2 and so on.
+	PositionalParameters []any
+}
+
+func (o *AnalyticsOptions) toRequest(statement string) (*analytics_v1.AnalyticsQueryRequest, error) {
+	req := &analytics_v1.AnalyticsQueryRequest{
+		Statement:       statement,
+		ScanConsistency: o.ScanConsistency,
+	}
+
+	if o.ReadOnly {
+		req.ReadOnly = &o.ReadOnly
+	}
+	if o.ClientContextID != "" {
+		req.ClientContextId = &o.ClientContextID
+	}
+	if o.Priority {
+		req.Priority = &o.Priority
+	}
+
+	var err error
+	req.NamedParameters, err = encodeNamedParameters(o.NamedParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	req.PositionalParameters, err = encodePositionalParameters(o.PositionalParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// analyticsScopeName formats the scope context of an analytics query in the same
+// form as the analytics service's query_context.
+func analyticsScopeName(bucketName, scopeName string) string {
+	return fmt.Sprintf("default:`%s`.`%s`", bucketName, scopeName)
+}
+
+// analyticsScopeBucket extracts the bucket name from an analytics scope name, if
+// it is in the form produced by analyticsScopeName.
+func analyticsScopeBucket(scopeName string) (string, bool) {
+	name := strings.TrimPrefix(scopeName, "default:")
+	if !strings.HasPrefix(name, "`") {
+		return "", false
+	}
+
+	bucketName, _, ok := strings.Cut(name[1:], "`")
+	return bucketName, ok && bucketName != ""
+}
+
+type AnalyticsWarning struct {
+	Code    uint32
+	Message string
+}
+
+type AnalyticsMetrics struct {
+	ElapsedTime      time.Duration
+	ExecutionTime    time.Duration
+	ResultCount      uint64
+	ResultSize       uint64
+	MutationCount    uint64
+	SortCount        uint64
+	ErrorCount       uint64
+	WarningCount     uint64
+	ProcessedObjects uint64
+}
+
+type AnalyticsMetaData struct {
+	RequestID       string
+	ClientContextID string
+	Status          string
+	Metrics         *AnalyticsMetrics
+	Warnings        []AnalyticsWarning
+	Signature       json.RawMessage
+}
+
+// AnalyticsResult iterates over the rows of an analytics query, rows must be fully
+// read before its metadata becomes available.
+type AnalyticsResult struct {
+	jsonRowStream
+
+	meta *analytics_v1.AnalyticsQueryResponse_MetaData
+}
+
+// NewAnalyticsResult wraps a stream returned by AnalyticsV1().AnalyticsQuery.
+// Closing the result before all rows are read drains the stream, cancel the context
+// used to create the stream to abandon it instead.
+func NewAnalyticsResult(stream analytics_v1.AnalyticsService_AnalyticsQueryClient) *AnalyticsResult {
+	return newAnalyticsResult(stream, nil)
+}
+
+func newAnalyticsResult(stream analytics_v1.AnalyticsService_AnalyticsQueryClient, cancel context.CancelFunc) *AnalyticsResult {
+	r := &AnalyticsResult{}
+	r.cancel = cancel
+	r.recv = func() ([][]byte, error) {
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.MetaData != nil {
+			r.meta = resp.MetaData
+		}
+
+		return resp.Rows, nil
+	}
+
+	return r
+}
+
+// Next advances to the next row, returning false once there are no more rows or an
+// error occurred, in which case Err returns the error.
+func (r *AnalyticsResult) Next() bool {
+	return r.next()
+}
+
+// Row decodes the current row into valuePtr.
+func (r *AnalyticsResult) Row(valuePtr interface{}) error {
+	return r.decodeRow(valuePtr)
+}
+
+// One decodes the first row into valuePtr and closes the result.
+func (r *AnalyticsResult) One(valuePtr interface{}) error {
+	return r.oneRow(valuePtr)
+}
+
+// Err returns any error which occurred during iteration.
+func (r *AnalyticsResult) Err() error {
+	return r.err
+}
+
+// Close stops iteration and releases the underlying stream.
+func (r *AnalyticsResult) Close() error {
+	return r.close()
+}
+
+// MetaData returns the metadata of the query, which is only available once all rows
+// have been read.
+func (r *AnalyticsResult) MetaData() (*AnalyticsMetaData, error) {
+	if !r.finished {
+		return nil, ErrResultNotFinished
+	}
+	if r.meta == nil {
+		return nil, ErrNoResult
+	}
+
+	meta := &AnalyticsMetaData{
+		RequestID:       r.meta.RequestId,
+		ClientContextID: r.meta.ClientContextId,
+		Status:          r.meta.Status,
+		Signature:       r.meta.Signature,
+	}
+
+	for _, warning := range r.meta.Warnings {
+		meta.Warnings = append(meta.Warnings, AnalyticsWarning{
+			Code:    warning.Code,
+			Message: warning.Message,
+		})
+	}
+
+	if metrics := r.meta.Metrics; metrics != nil {
+		meta.Metrics = &AnalyticsMetrics{
+			ElapsedTime:      metrics.ElapsedTime.AsDuration(),
+			ExecutionTime:    metrics.ExecutionTime.AsDuration(),
+			ResultCount:      metrics.ResultCount,
+			ResultSize:       metrics.ResultSize,
+			MutationCount:    metrics.MutationCount,
+			SortCount:        metrics.SortCount,
+			ErrorCount:       metrics.ErrorCount,
+			WarningCount:     metrics.WarningCount,
+			ProcessedObjects: metrics.ProcessedObjects,
+		}
+	}
+
+	return meta, nil
+}
+
+// AnalyticsRows returns an iterator which decodes each row of the result into a T.
+// The result is closed when iteration stops, any stream error is yielded last.
+func AnalyticsRows[T any](r *AnalyticsResult) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer r.Close()
+
+		for r.Next() {
+			var value T
+			err := r.Row(&value)
+			if !yield(value, err) {
+				return
+			}
+		}
+
+		if err := r.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+func executeAnalyticsQuery(ctx context.Context, client *RoutingClient, req *analytics_v1.AnalyticsQueryRequest) (*AnalyticsResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := client.AnalyticsV1().AnalyticsQuery(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return newAnalyticsResult(stream, cancel), nil
+}
+
+// AnalyticsQuery executes a cluster level analytics query.
+func (c *Cluster) AnalyticsQuery(ctx context.Context, statement string, opts *AnalyticsOptions) (*AnalyticsResult, error) {
+	if opts == nil {
+		opts = &AnalyticsOptions{}
+	}
+
+	req, err := opts.toRequest(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return executeAnalyticsQuery(ctx, c.client, req)
+}
+
+// AnalyticsQuery executes an analytics query with this scope as its query context.
+func (s *Scope) AnalyticsQuery(ctx context.Context, statement string, opts *AnalyticsOptions) (*AnalyticsResult, error) {
+	if opts == nil {
+		opts = &AnalyticsOptions{}
+	}
+
+	req, err := opts.toRequest(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	scopeName := analyticsScopeName(s.BucketName(), s.name)
+	req.AnalyticsScopeName = &scopeName
+
+	return executeAnalyticsQuery(ctx, s.bucket.cluster.client, req)
+}
@@ -3,16 +3,26 @@ package gocbcoreps
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"sync/atomic"
+
+	"google.golang.org/grpc/credentials"
 )
 
 type Authenticator interface {
 	isAuthenticator()
 }
 
+// identifiedAuthenticator is implemented by authenticators which know the identity
+// they authenticate as, so that it can be recorded for auditing.
+type identifiedAuthenticator interface {
+	authenticatedUser() string
+}
+
 type BasicAuthenticator struct {
 	encodedData atomic.Pointer[string]
+	username    atomic.Pointer[string]
 }
 
 // NewBasicAuthenticator creates PerRPCCredentials from the given username and password.
@@ -23,6 +33,7 @@ func NewBasicAuthenticator(username, password string) *BasicAuthenticator {
 	auth := &BasicAuthenticator{}
 
 	auth.encodedData.Store(&authValue)
+	auth.username.Store(&username)
 
 	return auth
 }
@@ -34,8 +45,10 @@ func (j *BasicAuthenticator) GetRequestMetadata(ctx context.Context, uri ...stri
 	}, nil
 }
 
+// RequireTransportSecurity reports that basic credentials must never be sent over
+// a plaintext connection. DialOptions.AllowInsecureCredentials can be used to opt out.
 func (j *BasicAuthenticator) RequireTransportSecurity() bool {
-	return false
+	return true
 }
 
 func (j *BasicAuthenticator) UpdateCredentials(username, password string) {
@@ -43,10 +56,25 @@ func (j *BasicAuthenticator) UpdateCredentials(username, password string) {
 	authValue := base64.StdEncoding.EncodeToString([]byte(basicAuth))
 
 	j.encodedData.Store(&authValue)
+	j.username.Store(&username)
+}
+
+func (j *BasicAuthenticator) authenticatedUser() string {
+	return *j.username.Load()
 }
 
 func (j *BasicAuthenticator) isAuthenticator() {}
 
+// insecurePerRPCCredentials wraps PerRPCCredentials so that they may be sent over
+// a plaintext connection, this is only used when explicitly opted into.
+type insecurePerRPCCredentials struct {
+	credentials.PerRPCCredentials
+}
+
+func (c insecurePerRPCCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
 type CertificateAuthenticator struct {
 	certificate atomic.Pointer[tls.Certificate]
 }
@@ -67,4 +95,27 @@ func (j *CertificateAuthenticator) UpdateCertificate(cert *tls.Certificate) {
 	j.certificate.Store(cert)
 }
 
+// authenticatedUser returns the subject common name of the client certificate,
+// which is what the server maps to a user by default.
+func (j *CertificateAuthenticator) authenticatedUser() string {
+	cert := j.certificate.Load()
+	if cert == nil {
+		return ""
+	}
+
+	leaf := cert.Leaf
+	if leaf == nil && len(cert.Certificate) > 0 {
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return ""
+		}
+		leaf = parsed
+	}
+	if leaf == nil {
+		return ""
+	}
+
+	return leaf.Subject.CommonName
+}
+
 func (j *CertificateAuthenticator) isAuthenticator() {}
@@ -0,0 +1,261 @@
+package gocbcoreps
+
+import (
+	"context"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultCredentialTTL = 5 * time.Minute
+
+const credentialRefreshTimeout = 10 * time.Second
+
+// Credentials are the username and password returned by a CredentialProvider.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialProvider provides credentials which can change over time, for example
+// secrets which are stored in, and rotated by, an external secret store.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderAuthenticator performs basic authentication using credentials
+// fetched from a CredentialProvider. Credentials are cached for the configured TTL
+// and refreshed asynchronously once they have expired.
+type CredentialProviderAuthenticator struct {
+	ttl time.Duration
+
+	lock        sync.Mutex
+	provider    CredentialProvider
+	encodedData string
+	username    string
+	expiresAt   time.Time
+	inflight    *credentialRefresh
+
+	// generation is incremented whenever the provider is replaced, so that results
+	// fetched from a previous provider are discarded.
+	generation uint64
+}
+
+// credentialRefresh is a fetch from the provider which is in progress, concurrent
+// refreshes wait for it rather than each calling the provider.
+type credentialRefresh struct {
+	generation  uint64
+	done        chan struct{}
+	encodedData string
+	username    string
+	err         error
+}
+
+// NewCredentialProviderAuthenticator creates an authenticator from the given provider. If
+// ttl is zero then a default of 5 minutes is used.
+func NewCredentialProviderAuthenticator(provider CredentialProvider, ttl time.Duration) *CredentialProviderAuthenticator {
+	if ttl <= 0 {
+		ttl = defaultCredentialTTL
+	}
+
+	return &CredentialProviderAuthenticator{
+		ttl:      ttl,
+		provider: provider,
+	}
+}
+
+func (j *CredentialProviderAuthenticator) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	j.lock.Lock()
+	encodedData := j.encodedData
+	expired := time.Now().After(j.expiresAt)
+	j.lock.Unlock()
+
+	if encodedData == "" {
+		// We have never successfully fetched credentials so we have to block.
+		var err error
+		encodedData, err = j.refresh(ctx)
+		if err != nil {
+			return nil, err
+		}
+	} else if expired {
+		j.refreshAsync()
+	}
+
+	return map[string]string{
+		"authorization": "Basic " + encodedData,
+	}, nil
+}
+
+func (j *CredentialProviderAuthenticator) RequireTransportSecurity() bool {
+	return true
+}
+
+// Refresh forces the credentials to be fetched from the provider immediately.
+func (j *CredentialProviderAuthenticator) Refresh(ctx context.Context) error {
+	_, err := j.refresh(ctx)
+	return err
+}
+
+// UpdateProvider swaps the provider in use and invalidates any cached credentials.
+func (j *CredentialProviderAuthenticator) UpdateProvider(provider CredentialProvider) {
+	j.lock.Lock()
+	j.provider = provider
+	j.generation++
+	j.expiresAt = time.Time{}
+	j.lock.Unlock()
+
+	j.refreshAsync()
+}
+
+func (j *CredentialProviderAuthenticator) refresh(ctx context.Context) (string, error) {
+	for {
+		j.lock.Lock()
+		if call := j.inflight; call != nil {
+			j.lock.Unlock()
+
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+
+			if j.isCurrent(call) {
+				return call.encodedData, call.err
+			}
+
+			// The provider was replaced while this call was in flight, so its
+			// result belongs to the old provider and we must fetch again.
+			continue
+		}
+
+		call := &credentialRefresh{
+			generation: j.generation,
+			done:       make(chan struct{}),
+		}
+		j.inflight = call
+		provider := j.provider
+		j.lock.Unlock()
+
+		creds, err := provider.Credentials(ctx)
+		if err == nil {
+			basicAuth := creds.Username + ":" + creds.Password
+			call.encodedData = base64.StdEncoding.EncodeToString([]byte(basicAuth))
+			call.username = creds.Username
+		}
+		call.err = err
+
+		j.lock.Lock()
+		current := call.generation == j.generation
+		if current && err == nil {
+			j.encodedData = call.encodedData
+			j.username = call.username
+			j.expiresAt = time.Now().Add(j.ttl)
+		}
+		j.inflight = nil
+		j.lock.Unlock()
+
+		close(call.done)
+
+		if current {
+			return call.encodedData, call.err
+		}
+	}
+}
+
+func (j *CredentialProviderAuthenticator) isCurrent(call *credentialRefresh) bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return call.generation == j.generation
+}
+
+func (j *CredentialProviderAuthenticator) refreshAsync() {
+	j.lock.Lock()
+	refreshing := j.inflight != nil && j.inflight.generation == j.generation
+	j.lock.Unlock()
+
+	if refreshing {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), credentialRefreshTimeout)
+		defer cancel()
+
+		// If the refresh fails then we keep using the stale credentials, the next
+		// request will trigger another attempt. A refresh from a replaced provider
+		// is waited for and then fetched again from the new provider.
+		_, _ = j.refresh(ctx)
+	}()
+}
+
+func (j *CredentialProviderAuthenticator) authenticatedUser() string {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.username
+}
+
+func (j *CredentialProviderAuthenticator) isAuthenticator() {}
+
+// credentialRefreshDialOptions returns interceptors which force a credential refresh
+// when the server rejects our credentials. Unary requests are retried once with the
+// refreshed credentials, streams only refresh as we cannot safely replay them once
+// they have started.
+func credentialRefreshDialOptions(auth *CredentialProviderAuthenticator) []grpc.DialOption {
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if status.Code(err) != codes.Unauthenticated {
+			return err
+		}
+
+		if refreshErr := auth.Refresh(ctx); refreshErr != nil {
+			return err
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if status.Code(err) == codes.Unauthenticated {
+			if refreshErr := auth.Refresh(ctx); refreshErr != nil {
+				return nil, err
+			}
+
+			s, err = streamer(ctx, desc, cc, method, opts...)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &credentialRefreshStream{ClientStream: s, auth: auth}, nil
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(unary),
+		grpc.WithChainStreamInterceptor(stream),
+	}
+}
+
+type credentialRefreshStream struct {
+	grpc.ClientStream
+	auth *CredentialProviderAuthenticator
+}
+
+func (s *credentialRefreshStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if status.Code(err) == codes.Unauthenticated {
+		s.auth.lock.Lock()
+		s.auth.expiresAt = time.Time{}
+		s.auth.lock.Unlock()
+
+		s.auth.refreshAsync()
+	}
+
+	return err
+}
@@ -0,0 +1,138 @@
+package gocbcoreps
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingCredentialProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (p *countingCredentialProvider) Credentials(ctx context.Context) (Credentials, error) {
+	p.calls.Add(1)
+	<-p.release
+	return Credentials{Username: "user", Password: "pass"}, nil
+}
+
+func TestCredentialProviderAuthenticatorCoalescesRefreshes(t *testing.T) {
+	provider := &countingCredentialProvider{release: make(chan struct{})}
+	auth := NewCredentialProviderAuthenticator(provider, time.Minute)
+
+	const callers = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			md, err := auth.GetRequestMetadata(context.Background())
+			if err != nil {
+				errs <- err
+				return
+			}
+			if md["authorization"] != "Basic dXNlcjpwYXNz" {
+				t.Errorf("unexpected authorization %q", md["authorization"])
+			}
+		}()
+	}
+
+	// Give every caller a chance to block on the in-flight refresh.
+	time.Sleep(50 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("expected a single refresh, got %d", calls)
+	}
+}
+
+func TestCredentialProviderAuthenticatorConcurrentForcedRefresh(t *testing.T) {
+	provider := &countingCredentialProvider{release: make(chan struct{})}
+	auth := NewCredentialProviderAuthenticator(provider, time.Minute)
+
+	const callers = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := auth.Refresh(context.Background()); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("expected a single refresh, got %d", calls)
+	}
+}
+
+type staticCredentialProvider struct {
+	creds Credentials
+	calls atomic.Int32
+}
+
+func (p *staticCredentialProvider) Credentials(ctx context.Context) (Credentials, error) {
+	p.calls.Add(1)
+	return p.creds, nil
+}
+
+func TestCredentialProviderAuthenticatorUpdateProviderDuringRefresh(t *testing.T) {
+	oldProvider := &countingCredentialProvider{release: make(chan struct{})}
+	auth := NewCredentialProviderAuthenticator(oldProvider, time.Hour)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- auth.Refresh(context.Background())
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for oldProvider.calls.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("refresh never reached the old provider")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Replace the provider while the old one is still being called, its result
+	// must not overwrite the invalidation.
+	newProvider := &staticCredentialProvider{creds: Credentials{Username: "new", Password: "secret"}}
+	auth.UpdateProvider(newProvider)
+	close(oldProvider.release)
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	md, err := auth.GetRequestMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if md["authorization"] != "Basic bmV3OnNlY3JldA==" {
+		t.Fatalf("expected credentials from the new provider, got %q", md["authorization"])
+	}
+	if user := auth.authenticatedUser(); user != "new" {
+		t.Fatalf("expected the new provider's user, got %q", user)
+	}
+	if newProvider.calls.Load() == 0 {
+		t.Fatalf("expected the new provider to be called")
+	}
+}
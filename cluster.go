@@ -0,0 +1,119 @@
+package gocbcoreps
+
+const (
+	defaultScopeName      = "_default"
+	defaultCollectionName = "_default"
+)
+
+// Cluster provides typed access to a cluster on top of a RoutingClient.
+type Cluster struct {
+	client     *RoutingClient
+	transcoder Transcoder
+}
+
+type ClusterOptions struct {
+	// Transcoder is used by default for all KV operations, by default this is a
+	// JSONTranscoder.
+	Transcoder Transcoder
+}
+
+func NewCluster(client *RoutingClient, opts *ClusterOptions) *Cluster {
+	if opts == nil {
+		opts = &ClusterOptions{}
+	}
+
+	transcoder := opts.Transcoder
+	if transcoder == nil {
+		transcoder = NewJSONTranscoder()
+	}
+
+	return &Cluster{
+		client:     client,
+		transcoder: transcoder,
+	}
+}
+
+// Client returns the RoutingClient which this cluster uses.
+func (c *Cluster) Client() *RoutingClient {
+	return c.client
+}
+
+func (c *Cluster) Bucket(name string) *Bucket {
+	return &Bucket{
+		cluster: c,
+		name:    name,
+	}
+}
+
+type Bucket struct {
+	cluster *Cluster
+	name    string
+}
+
+func (b *Bucket) Name() string {
+	return b.name
+}
+
+func (b *Bucket) Scope(name string) *Scope {
+	return &Scope{
+		bucket: b,
+		name:   name,
+	}
+}
+
+func (b *Bucket) DefaultScope() *Scope {
+	return b.Scope(defaultScopeName)
+}
+
+func (b *Bucket) DefaultCollection() *Collection {
+	return b.DefaultScope().Collection(defaultCollectionName)
+}
+
+type Scope struct {
+	bucket *Bucket
+	name   string
+}
+
+func (s *Scope) Name() string {
+	return s.name
+}
+
+func (s *Scope) BucketName() string {
+	return s.bucket.name
+}
+
+func (s *Scope) Collection(name string) *Collection {
+	return &Collection{
+		scope: s,
+		name:  name,
+	}
+}
+
+type Collection struct {
+	scope *Scope
+	name  string
+}
+
+func (c *Collection) Name() string {
+	return c.name
+}
+
+func (c *Collection) ScopeName() string {
+	return c.scope.name
+}
+
+func (c *Collection) BucketName() string {
+	return c.scope.bucket.name
+}
+
+func (c *Collection) client() *RoutingClient {
+	return c.scope.bucket.cluster.client
+}
+
+func (c *Collection) transcoder(override Transcoder) Transcoder {
+	if override != nil {
+		return override
+	}
+
+	return c.scope.bucket.cluster.transcoder
+}
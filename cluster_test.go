@@ -0,0 +1,44 @@
+package gocbcoreps
+
+import "testing"
+
+func TestClusterNames(t *testing.T) {
+	cluster := NewCluster(&RoutingClient{}, nil)
+
+	coll := cluster.Bucket("travel").Scope("inventory").Collection("airline")
+	if coll.BucketName() != "travel" || coll.ScopeName() != "inventory" || coll.Name() != "airline" {
+		t.Fatalf("unexpected collection path %s.%s.%s", coll.BucketName(), coll.ScopeName(), coll.Name())
+	}
+
+	def := cluster.Bucket("travel").DefaultCollection()
+	if def.ScopeName() != defaultScopeName || def.Name() != defaultCollectionName {
+		t.Fatalf("unexpected default collection path %s.%s", def.ScopeName(), def.Name())
+	}
+	if scope := cluster.Bucket("travel").DefaultScope(); scope.Name() != defaultScopeName || scope.BucketName() != "travel" {
+		t.Fatalf("unexpected default scope %s.%s", scope.BucketName(), scope.Name())
+	}
+}
+
+func TestClusterTranscoder(t *testing.T) {
+	override := NewRawStringTranscoder()
+
+	tests := []struct {
+		name     string
+		opts     *ClusterOptions
+		override Transcoder
+		want     func(Transcoder) bool
+	}{
+		{"default", nil, nil, func(tc Transcoder) bool { _, ok := tc.(*JSONTranscoder); return ok }},
+		{"cluster", &ClusterOptions{Transcoder: NewLegacyTranscoder()}, nil, func(tc Transcoder) bool { _, ok := tc.(*LegacyTranscoder); return ok }},
+		{"operation", &ClusterOptions{Transcoder: NewLegacyTranscoder()}, override, func(tc Transcoder) bool { return tc == override }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coll := NewCluster(&RoutingClient{}, tt.opts).Bucket("b").DefaultCollection()
+			if got := coll.transcoder(tt.override); !tt.want(got) {
+				t.Fatalf("unexpected transcoder %T", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package gocbcoreps
+
+import (
+	"context"
+	"time"
+
+	"github.com/couchbase/goprotostellar/genproto/kv_v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Expiries longer than this are sent as an absolute time, matching the server's
+// own interpretation of relative expiries.
+const maxRelativeExpiry = 30 * 24 * time.Hour
+
+type GetOptions struct {
+	Transcoder Transcoder
+	Project    []string
+}
+
+type GetResult struct {
+	Cas    uint64
+	Expiry time.Time
+
+	content    []byte
+	flags      uint32
+	transcoder Transcoder
+}
+
+// Content decodes the document into valuePtr using the transcoder for the operation.
+func (r *GetResult) Content(valuePtr interface{}) error {
+	return r.transcoder.Decode(r.content, r.flags, valuePtr)
+}
+
+type MutationResult struct {
+	Cas           uint64
+	MutationToken *kv_v1.MutationToken
+}
+
+type UpsertOptions struct {
+	Transcoder      Transcoder
+	Expiry          time.Duration
+	PreserveExpiry  bool
+	DurabilityLevel *kv_v1.DurabilityLevel
+}
+
+type InsertOptions struct {
+	Transcoder      Transcoder
+	Expiry          time.Duration
+	DurabilityLevel *kv_v1.DurabilityLevel
+}
+
+type ReplaceOptions struct {
+	Transcoder      Transcoder
+	Expiry          time.Duration
+	Cas             uint64
+	DurabilityLevel *kv_v1.DurabilityLevel
+}
+
+type RemoveOptions struct {
+	Cas             uint64
+	DurabilityLevel *kv_v1.DurabilityLevel
+}
+
+func (c *Collection) Get(ctx context.Context, key string, opts *GetOptions) (*GetResult, error) {
+	return c.get(ctx, c.client().KvV1(), key, opts)
+}
+
+func (c *Collection) get(ctx context.Context, kv kv_v1.KvServiceClient, key string, opts *GetOptions) (*GetResult, error) {
+	if opts == nil {
+		opts = &GetOptions{}
+	}
+
+	resp, err := kv.Get(ctx, &kv_v1.GetRequest{
+		BucketName:     c.BucketName(),
+		ScopeName:      c.ScopeName(),
+		CollectionName: c.name,
+		Key:            key,
+		Project:        opts.Project,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	switch v := resp.Content.(type) {
+	case *kv_v1.GetResponse_ContentUncompressed:
+		content = v.ContentUncompressed
+	case *kv_v1.GetResponse_ContentCompressed:
+		content, err = snappyDecode(v.ContentCompressed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var expiry time.Time
+	if resp.Expiry != nil {
+		expiry = resp.Expiry.AsTime()
+	}
+
+	return &GetResult{
+		Cas:        resp.Cas,
+		Expiry:     expiry,
+		content:    content,
+		flags:      resp.ContentFlags,
+		transcoder: c.transcoder(opts.Transcoder),
+	}, nil
+}
+
+// Get fetches a document and decodes it into a value of type T.
+func Get[T any](ctx context.Context, c *Collection, key string, opts *GetOptions) (T, *GetResult, error) {
+	var value T
+
+	res, err := c.Get(ctx, key, opts)
+	if err != nil {
+		return value, nil, err
+	}
+
+	if err := res.Content(&value); err != nil {
+		return value, nil, err
+	}
+
+	return value, res, nil
+}
+
+func (c *Collection) Upsert(ctx context.Context, key string, value interface{}, opts *UpsertOptions) (*MutationResult, error) {
+	return c.upsert(ctx, c.client().KvV1(), key, value, opts)
+}
+
+func (c *Collection) upsert(ctx context.Context, kv kv_v1.KvServiceClient, key string, value interface{}, opts *UpsertOptions) (*MutationResult, error) {
+	if opts == nil {
+		opts = &UpsertOptions{}
+	}
+
+	content, flags, err := c.transcoder(opts.Transcoder).Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &kv_v1.UpsertRequest{
+		BucketName:      c.BucketName(),
+		ScopeName:       c.ScopeName(),
+		CollectionName:  c.name,
+		Key:             key,
+		Content:         &kv_v1.UpsertRequest_ContentUncompressed{ContentUncompressed: content},
+		ContentFlags:    flags,
+		DurabilityLevel: opts.DurabilityLevel,
+	}
+	if opts.Expiry > 0 {
+		if opts.Expiry > maxRelativeExpiry {
+			req.Expiry = &kv_v1.UpsertRequest_ExpiryTime{ExpiryTime: expiryTime(opts.Expiry)}
+		} else {
+			req.Expiry = &kv_v1.UpsertRequest_ExpirySecs{ExpirySecs: expirySecs(opts.Expiry)}
+		}
+	}
+	if opts.PreserveExpiry {
+		req.PreserveExpiryOnExisting = &opts.PreserveExpiry
+	}
+
+	resp, err := kv.Upsert(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MutationResult{
+		Cas:           resp.Cas,
+		MutationToken: resp.MutationToken,
+	}, nil
+}
+
+func (c *Collection) Insert(ctx context.Context, key string, value interface{}, opts *InsertOptions) (*MutationResult, error) {
+	if opts == nil {
+		opts = &InsertOptions{}
+	}
+
+	content, flags, err := c.transcoder(opts.Transcoder).Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &kv_v1.InsertRequest{
+		BucketName:      c.BucketName(),
+		ScopeName:       c.ScopeName(),
+		CollectionName:  c.name,
+		Key:             key,
+		Content:         &kv_v1.InsertRequest_ContentUncompressed{ContentUncompressed: content},
+		ContentFlags:    flags,
+		DurabilityLevel: opts.DurabilityLevel,
+	}
+	if opts.Expiry > 0 {
+		if opts.Expiry > maxRelativeExpiry {
+			req.Expiry = &kv_v1.InsertRequest_ExpiryTime{ExpiryTime: expiryTime(opts.Expiry)}
+		} else {
+			req.Expiry = &kv_v1.InsertRequest_ExpirySecs{ExpirySecs: expirySecs(opts.Expiry)}
+		}
+	}
+
+	resp, err := c.client().KvV1().Insert(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MutationResult{
+		Cas:           resp.Cas,
+		MutationToken: resp.MutationToken,
+	}, nil
+}
+
+// Replace replaces an existing document. If opts.Cas is specified then the replace
+// only succeeds if the document has not been modified since that CAS was read.
+func (c *Collection) Replace(ctx context.Context, key string, value interface{}, opts *ReplaceOptions) (*MutationResult, error) {
+	if opts == nil {
+		opts = &ReplaceOptions{}
+	}
+
+	content, flags, err := c.transcoder(opts.Transcoder).Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &kv_v1.ReplaceRequest{
+		BucketName:      c.BucketName(),
+		ScopeName:       c.ScopeName(),
+		CollectionName:  c.name,
+		Key:             key,
+		Content:         &kv_v1.ReplaceRequest_ContentUncompressed{ContentUncompressed: content},
+		ContentFlags:    flags,
+		DurabilityLevel: opts.DurabilityLevel,
+	}
+	if opts.Cas > 0 {
+		req.Cas = &opts.Cas
+	}
+	if opts.Expiry > 0 {
+		if opts.Expiry > maxRelativeExpiry {
+			req.Expiry = &kv_v1.ReplaceRequest_ExpiryTime{ExpiryTime: expiryTime(opts.Expiry)}
+		} else {
+			req.Expiry = &kv_v1.ReplaceRequest_ExpirySecs{ExpirySecs: expirySecs(opts.Expiry)}
+		}
+	}
+
+	resp, err := c.client().KvV1().Replace(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MutationResult{
+		Cas:           resp.Cas,
+		MutationToken: resp.MutationToken,
+	}, nil
+}
+
+func (c *Collection) Remove(ctx context.Context, key string, opts *RemoveOptions) (*MutationResult, error) {
+	return c.remove(ctx, c.client().KvV1(), key, opts)
+}
+
+func (c *Collection) remove(ctx context.Context, kv kv_v1.KvServiceClient, key string, opts *RemoveOptions) (*MutationResult, error) {
+	if opts == nil {
+		opts = &RemoveOptions{}
+	}
+
+	req := &kv_v1.RemoveRequest{
+		BucketName:      c.BucketName(),
+		ScopeName:       c.ScopeName(),
+		CollectionName:  c.name,
+		Key:             key,
+		DurabilityLevel: opts.DurabilityLevel,
+	}
+	if opts.Cas > 0 {
+		req.Cas = &opts.Cas
+	}
+
+	resp, err := kv.Remove(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MutationResult{
+		Cas:           resp.Cas,
+		MutationToken: resp.MutationToken,
+	}, nil
+}
+
+func expirySecs(expiry time.Duration) uint32 {
+	secs := uint32(expiry / time.Second)
+	if secs == 0 {
+		// A zero expiry means no expiry, so round sub-second expiries up.
+		secs = 1
+	}
+
+	return secs
+}
+
+func expiryTime(expiry time.Duration) *timestamppb.Timestamp {
+	return timestamppb.New(time.Now().Add(expiry))
+}
@@ -0,0 +1,166 @@
+package gocbcoreps
+
+import (
+	"context"
+	"sync"
+
+	"github.com/couchbase/goprotostellar/genproto/kv_v1"
+)
+
+const defaultBulkConcurrency = 16
+
+type BulkGetOptions struct {
+	// Concurrency is the maximum number of requests in flight at once, by default
+	// this is 16.
+	Concurrency int
+	GetOptions  *GetOptions
+}
+
+type BulkGetResult struct {
+	Key    string
+	Result *GetResult
+	Err    error
+}
+
+type BulkUpsertItem struct {
+	Key   string
+	Value interface{}
+}
+
+type BulkUpsertOptions struct {
+	// Concurrency is the maximum number of requests in flight at once, by default
+	// this is 16.
+	Concurrency   int
+	UpsertOptions *UpsertOptions
+}
+
+type BulkRemoveOptions struct {
+	// Concurrency is the maximum number of requests in flight at once, by default
+	// this is 16.
+	Concurrency   int
+	RemoveOptions *RemoveOptions
+}
+
+type BulkMutationResult struct {
+	Key    string
+	Result *MutationResult
+	Err    error
+}
+
+// BulkGet fetches many documents at once. Results are returned in the same order
+// as keys, with a per-key error for any which failed.
+func (c *Collection) BulkGet(ctx context.Context, keys []string, opts *BulkGetOptions) []BulkGetResult {
+	if opts == nil {
+		opts = &BulkGetOptions{}
+	}
+
+	results := make([]BulkGetResult, len(keys))
+	c.runBulk(ctx, keys, opts.Concurrency, func(ctx context.Context, kv kv_v1.KvServiceClient, idx int) {
+		res, err := c.get(ctx, kv, keys[idx], opts.GetOptions)
+		results[idx] = BulkGetResult{Key: keys[idx], Result: res, Err: err}
+	})
+
+	return results
+}
+
+// BulkUpsert upserts many documents at once. Results are returned in the same order
+// as items, with a per-key error for any which failed.
+func (c *Collection) BulkUpsert(ctx context.Context, items []BulkUpsertItem, opts *BulkUpsertOptions) []BulkMutationResult {
+	if opts == nil {
+		opts = &BulkUpsertOptions{}
+	}
+
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item.Key
+	}
+
+	results := make([]BulkMutationResult, len(items))
+	c.runBulk(ctx, keys, opts.Concurrency, func(ctx context.Context, kv kv_v1.KvServiceClient, idx int) {
+		res, err := c.upsert(ctx, kv, items[idx].Key, items[idx].Value, opts.UpsertOptions)
+		results[idx] = BulkMutationResult{Key: items[idx].Key, Result: res, Err: err}
+	})
+
+	return results
+}
+
+// BulkRemove removes many documents at once. Results are returned in the same order
+// as keys, with a per-key error for any which failed.
+func (c *Collection) BulkRemove(ctx context.Context, keys []string, opts *BulkRemoveOptions) []BulkMutationResult {
+	if opts == nil {
+		opts = &BulkRemoveOptions{}
+	}
+
+	results := make([]BulkMutationResult, len(keys))
+	c.runBulk(ctx, keys, opts.Concurrency, func(ctx context.Context, kv kv_v1.KvServiceClient, idx int) {
+		res, err := c.remove(ctx, kv, keys[idx], opts.RemoveOptions)
+		results[idx] = BulkMutationResult{Key: keys[idx], Result: res, Err: err}
+	})
+
+	return results
+}
+
+// runBulk groups keys by the connection which they are routed to, and then runs fn
+// for each key with at most concurrency calls in flight. Dispatch alternates
+// between groups so that no single node is sent all of the early requests.
+func (c *Collection) runBulk(ctx context.Context, keys []string, concurrency int, fn func(ctx context.Context, kv kv_v1.KvServiceClient, idx int)) {
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+
+	client := c.client()
+	bucketName := c.BucketName()
+
+	// TODO: Once key routing is implemented, this will group keys by the node
+	// which owns their vbucket.
+	var groupConns []*routingConn
+	var groups [][]int
+	groupIdx := make(map[*routingConn]int)
+	for idx, key := range keys {
+		conn := client.fetchConnForKey(bucketName, key)
+
+		gIdx, ok := groupIdx[conn]
+		if !ok {
+			gIdx = len(groups)
+			groupIdx[conn] = gIdx
+			groupConns = append(groupConns, conn)
+			groups = append(groups, nil)
+		}
+
+		groups[gIdx] = append(groups[gIdx], idx)
+	}
+
+	kvs := make([]kv_v1.KvServiceClient, len(groupConns))
+	for i, conn := range groupConns {
+		kvs[i] = &routingImpl_KvV1{client: client, conn: conn}
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for pos := 0; ; pos++ {
+		dispatched := false
+		for gIdx, group := range groups {
+			if pos >= len(group) {
+				continue
+			}
+			dispatched = true
+
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(kv kv_v1.KvServiceClient, idx int) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				fn(ctx, kv, idx)
+			}(kvs[gIdx], group[pos])
+		}
+
+		if !dispatched {
+			break
+		}
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,184 @@
+package gocbcoreps
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/couchbase/goprotostellar/genproto/kv_v1"
+	"github.com/golang/snappy"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeKvClient records the last request made and returns canned responses.
+type fakeKvClient struct {
+	kv_v1.KvServiceClient
+
+	getReq    *kv_v1.GetRequest
+	getResp   *kv_v1.GetResponse
+	upsertReq *kv_v1.UpsertRequest
+	removeReq *kv_v1.RemoveRequest
+}
+
+func (c *fakeKvClient) Get(ctx context.Context, in *kv_v1.GetRequest, opts ...grpc.CallOption) (*kv_v1.GetResponse, error) {
+	c.getReq = in
+	return c.getResp, nil
+}
+
+func (c *fakeKvClient) Upsert(ctx context.Context, in *kv_v1.UpsertRequest, opts ...grpc.CallOption) (*kv_v1.UpsertResponse, error) {
+	c.upsertReq = in
+	return &kv_v1.UpsertResponse{Cas: 2}, nil
+}
+
+func (c *fakeKvClient) Remove(ctx context.Context, in *kv_v1.RemoveRequest, opts ...grpc.CallOption) (*kv_v1.RemoveResponse, error) {
+	c.removeReq = in
+	return &kv_v1.RemoveResponse{Cas: 3}, nil
+}
+
+func testCollection() *Collection {
+	return NewCluster(&RoutingClient{}, nil).Bucket("b").Scope("s").Collection("c")
+}
+
+func TestCollectionGet(t *testing.T) {
+	doc := []byte(`{"name":"a","age":1}`)
+	expiry := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name    string
+		resp    *kv_v1.GetResponse
+		wantErr error
+	}{
+		{"uncompressed", &kv_v1.GetResponse{
+			Content:      &kv_v1.GetResponse_ContentUncompressed{ContentUncompressed: doc},
+			ContentFlags: commonFlagsFormatJSON,
+			Cas:          1,
+			Expiry:       timestamppb.New(expiry),
+		}, nil},
+		{"compressed", &kv_v1.GetResponse{
+			Content:      &kv_v1.GetResponse_ContentCompressed{ContentCompressed: snappy.Encode(nil, doc)},
+			ContentFlags: commonFlagsFormatJSON,
+			Cas:          1,
+			Expiry:       timestamppb.New(expiry),
+		}, nil},
+		{"corrupt compressed", &kv_v1.GetResponse{
+			Content:      &kv_v1.GetResponse_ContentCompressed{ContentCompressed: []byte{0xff}},
+			ContentFlags: commonFlagsFormatJSON,
+		}, ErrDecodingFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv := &fakeKvClient{getResp: tt.resp}
+			res, err := testCollection().get(context.Background(), kv, "key", &GetOptions{Project: []string{"name"}})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req := kv.getReq
+			if req.BucketName != "b" || req.ScopeName != "s" || req.CollectionName != "c" || req.Key != "key" {
+				t.Fatalf("unexpected request %v", req)
+			}
+			if len(req.Project) != 1 || req.Project[0] != "name" {
+				t.Fatalf("expected projection to be sent, got %v", req.Project)
+			}
+
+			if res.Cas != 1 || !res.Expiry.Equal(expiry) {
+				t.Fatalf("unexpected result cas %d expiry %v", res.Cas, res.Expiry)
+			}
+
+			var got transcoderTestDoc
+			if err := res.Content(&got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Name != "a" || got.Age != 1 {
+				t.Fatalf("unexpected content %+v", got)
+			}
+		})
+	}
+}
+
+func TestCollectionUpsert(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         *UpsertOptions
+		wantFlags    uint32
+		wantSecs     uint32
+		wantTime     bool
+		wantPreserve bool
+	}{
+		{"defaults", nil, commonFlagsFormatJSON, 0, false, false},
+		{"relative expiry", &UpsertOptions{Expiry: 10 * time.Second}, commonFlagsFormatJSON, 10, false, false},
+		{"sub-second expiry", &UpsertOptions{Expiry: time.Millisecond}, commonFlagsFormatJSON, 1, false, false},
+		{"absolute expiry", &UpsertOptions{Expiry: maxRelativeExpiry + time.Hour}, commonFlagsFormatJSON, 0, true, false},
+		{"preserve expiry", &UpsertOptions{PreserveExpiry: true}, commonFlagsFormatJSON, 0, false, true},
+		{"transcoder", &UpsertOptions{Transcoder: NewRawStringTranscoder()}, commonFlagsFormatString, 0, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := interface{}(transcoderTestDoc{Name: "a"})
+			if tt.opts != nil && tt.opts.Transcoder != nil {
+				value = "a"
+			}
+
+			kv := &fakeKvClient{}
+			res, err := testCollection().upsert(context.Background(), kv, "key", value, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Cas != 2 {
+				t.Fatalf("expected cas 2, got %d", res.Cas)
+			}
+
+			req := kv.upsertReq
+			if req.ContentFlags != tt.wantFlags {
+				t.Fatalf("expected flags %#x, got %#x", tt.wantFlags, req.ContentFlags)
+			}
+			if got := req.GetExpirySecs(); got != tt.wantSecs {
+				t.Fatalf("expected expiry secs %d, got %d", tt.wantSecs, got)
+			}
+			if got := req.GetExpiryTime() != nil; got != tt.wantTime {
+				t.Fatalf("expected absolute expiry %t, got %t", tt.wantTime, got)
+			}
+			if got := req.GetPreserveExpiryOnExisting(); got != tt.wantPreserve {
+				t.Fatalf("expected preserve expiry %t, got %t", tt.wantPreserve, got)
+			}
+		})
+	}
+}
+
+func TestCollectionUpsertEncodingFailure(t *testing.T) {
+	kv := &fakeKvClient{}
+	_, err := testCollection().upsert(context.Background(), kv, "key", []byte("raw"), nil)
+	if !errors.Is(err, ErrEncodingFailure) {
+		t.Fatalf("expected ErrEncodingFailure, got %v", err)
+	}
+	if kv.upsertReq != nil {
+		t.Fatal("expected no request to be sent")
+	}
+}
+
+func TestCollectionRemoveCas(t *testing.T) {
+	kv := &fakeKvClient{}
+	if _, err := testCollection().remove(context.Background(), kv, "key", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kv.removeReq.Cas != nil {
+		t.Fatalf("expected no cas, got %d", kv.removeReq.GetCas())
+	}
+
+	if _, err := testCollection().remove(context.Background(), kv, "key", &RemoveOptions{Cas: 5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kv.removeReq.GetCas() != 5 {
+		t.Fatalf("expected cas 5, got %d", kv.removeReq.GetCas())
+	}
+}
@@ -0,0 +1,181 @@
+package gocbcoreps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip" // also registers the gzip compressor
+	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// CompressionGzip is the name of the built-in gzip compressor.
+	CompressionGzip = gzip.Name
+
+	// CompressionNone disables compression, it can be used in service overrides.
+	CompressionNone = encoding.Identity
+)
+
+const compressionMeterName = "github.com/couchbase/gocbcoreps"
+
+// CompressionOptions configures compression of outgoing requests. Responses are
+// compressed at the discretion of the server using any registered compressor.
+type CompressionOptions struct {
+	// Compressor is the name of the compressor to use, for example CompressionGzip.
+	Compressor string
+
+	// MinSize is the smallest unary request which will be compressed, smaller
+	// requests are sent uncompressed.
+	MinSize int
+
+	// ServiceOverrides overrides Compressor for specific grpc services, keyed by
+	// the full service name, for example "couchbase.kv.v1.KvService". Use
+	// CompressionNone to disable compression for a service.
+	ServiceOverrides map[string]string
+}
+
+// RegisterCompressor registers an additional compressor, such as zstd or snappy,
+// which can then be used by name in CompressionOptions. Like
+// encoding.RegisterCompressor it must only be called during initialization.
+func RegisterCompressor(c encoding.Compressor) {
+	encoding.RegisterCompressor(c)
+}
+
+// validate checks that every compressor named by the options has been registered,
+// as grpc would otherwise only fail each RPC which uses it.
+func (o *CompressionOptions) validate() error {
+	if err := checkCompressor(o.Compressor); err != nil {
+		return err
+	}
+
+	for service, compressor := range o.ServiceOverrides {
+		if err := checkCompressor(compressor); err != nil {
+			return fmt.Errorf("override for %s: %w", service, err)
+		}
+	}
+
+	return nil
+}
+
+func checkCompressor(name string) error {
+	if name == "" || name == CompressionNone {
+		return nil
+	}
+
+	if encoding.GetCompressor(name) == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownCompressor, name)
+	}
+
+	return nil
+}
+
+func (o *CompressionOptions) compressorFor(method string) string {
+	// Methods are of the form "/package.Service/Method".
+	service := strings.TrimPrefix(method, "/")
+	if idx := strings.LastIndex(service, "/"); idx >= 0 {
+		service = service[:idx]
+	}
+
+	if compressor, ok := o.ServiceOverrides[service]; ok {
+		return compressor
+	}
+
+	return o.Compressor
+}
+
+// compressionDialOptions returns interceptors which select a compressor per RPC.
+func compressionDialOptions(opts *CompressionOptions, meterProvider metric.MeterProvider) []grpc.DialOption {
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		compressor := opts.compressorFor(method)
+		if compressor != "" && compressor != CompressionNone {
+			msg, ok := req.(proto.Message)
+			if !ok || proto.Size(msg) >= opts.MinSize {
+				callOpts = append(callOpts, grpc.UseCompressor(compressor))
+			}
+		}
+
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		// The request has not been sent yet, so the minimum size cannot be applied.
+		compressor := opts.compressorFor(method)
+		if compressor != "" && compressor != CompressionNone {
+			callOpts = append(callOpts, grpc.UseCompressor(compressor))
+		}
+
+		return streamer(ctx, desc, cc, method, callOpts...)
+	}
+
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(unary),
+		grpc.WithChainStreamInterceptor(stream),
+		grpc.WithStatsHandler(newCompressionStatsHandler(meterProvider)),
+	}
+}
+
+type compressionMethodCtxKey struct{}
+
+// compressionStatsHandler records the ratio between the compressed and
+// uncompressed size of each message.
+type compressionStatsHandler struct {
+	ratio metric.Float64Histogram
+}
+
+func newCompressionStatsHandler(meterProvider metric.MeterProvider) *compressionStatsHandler {
+	meter := meterProvider.Meter(compressionMeterName)
+
+	// If the instrument cannot be created then a no-op one is returned alongside
+	// the error, so we can safely ignore it.
+	ratio, _ := meter.Float64Histogram("rpc.client.compression_ratio",
+		metric.WithDescription("Ratio of compressed to uncompressed message size."))
+
+	return &compressionStatsHandler{
+		ratio: ratio,
+	}
+}
+
+func (h *compressionStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, compressionMethodCtxKey{}, info.FullMethodName)
+}
+
+func (h *compressionStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	var direction string
+	var length, compressedLength int
+	switch p := s.(type) {
+	case *stats.OutPayload:
+		direction, length, compressedLength = "sent", p.Length, p.CompressedLength
+	case *stats.InPayload:
+		direction, length, compressedLength = "received", p.Length, p.CompressedLength
+	default:
+		return
+	}
+
+	if length == 0 || compressedLength == length {
+		// Uncompressed messages would skew our ratio.
+		return
+	}
+
+	method, _ := ctx.Value(compressionMethodCtxKey{}).(string)
+	h.ratio.Record(ctx, float64(compressedLength)/float64(length), metric.WithAttributes(
+		attribute.String("rpc.method", method),
+		attribute.String("direction", direction),
+	))
+}
+
+func (h *compressionStatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *compressionStatsHandler) HandleConn(context.Context, stats.ConnStats) {}
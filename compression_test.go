@@ -0,0 +1,63 @@
+package gocbcoreps
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompressionOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CompressionOptions
+		err  error
+	}{
+		{name: "unset"},
+		{name: "gzip", opts: CompressionOptions{Compressor: CompressionGzip}},
+		{name: "none", opts: CompressionOptions{Compressor: CompressionNone}},
+		{name: "unregistered", opts: CompressionOptions{Compressor: "zstd"}, err: ErrUnknownCompressor},
+		{
+			name: "override disables",
+			opts: CompressionOptions{
+				Compressor:       CompressionGzip,
+				ServiceOverrides: map[string]string{"couchbase.kv.v1.KvService": CompressionNone},
+			},
+		},
+		{
+			name: "unregistered override",
+			opts: CompressionOptions{ServiceOverrides: map[string]string{"couchbase.kv.v1.KvService": "gzp"}},
+			err:  ErrUnknownCompressor,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.opts.validate(); !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestCompressionOptionsCompressorFor(t *testing.T) {
+	opts := &CompressionOptions{
+		Compressor:       CompressionGzip,
+		ServiceOverrides: map[string]string{"couchbase.kv.v1.KvService": CompressionNone},
+	}
+
+	if c := opts.compressorFor("/couchbase.kv.v1.KvService/Get"); c != CompressionNone {
+		t.Fatalf("expected the override, got %q", c)
+	}
+	if c := opts.compressorFor("/couchbase.query.v1.QueryService/Query"); c != CompressionGzip {
+		t.Fatalf("expected the default compressor, got %q", c)
+	}
+}
+
+func TestDialSeedsRejectsUnknownCompressor(t *testing.T) {
+	_, err := DialSeeds([]string{"cb.local"}, &DialOptions{
+		InsecureTransport: true,
+		Compression:       &CompressionOptions{Compressor: "zstd"},
+	})
+	if !errors.Is(err, ErrUnknownCompressor) {
+		t.Fatalf("expected ErrUnknownCompressor, got %v", err)
+	}
+}
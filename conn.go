@@ -8,6 +8,7 @@ import (
 	"github.com/couchbase/goprotostellar/genproto/query_v1"
 	"github.com/couchbase/goprotostellar/genproto/routing_v2"
 	"github.com/couchbase/goprotostellar/genproto/search_v1"
+	"github.com/couchbase/goprotostellar/genproto/view_v1"
 )
 
 type Conn interface {
@@ -18,4 +19,5 @@ type Conn interface {
 	BucketV1() admin_bucket_v1.BucketAdminServiceClient
 	AnalyticsV1() analytics_v1.AnalyticsServiceClient
 	SearchV1() search_v1.SearchServiceClient
+	ViewV1() view_v1.ViewServiceClient
 }
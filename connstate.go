@@ -6,4 +6,8 @@ const (
 	ConnStateOffline ConnState = iota
 	ConnStateDegraded
 	ConnStateOnline
+
+	// ConnStateIdle indicates that connections have been closed after being idle,
+	// they will reconnect on demand.
+	ConnStateIdle
 )
@@ -0,0 +1,101 @@
+package gocbcoreps
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/couchbase/goprotostellar/genproto/kv_v1"
+)
+
+// ConsistencySession records the mutation tokens of every mutation made with a
+// context carrying the session, so that later queries made with the same context
+// can read their own writes without needing request_plus consistency.
+//
+// Sessions apply to query requests only. The search protocol has no consistency
+// vector, so searches made with a session do not wait for its mutations to be
+// indexed.
+type ConsistencySession struct {
+	lock   sync.Mutex
+	tokens map[string]map[uint32]*kv_v1.MutationToken
+}
+
+func NewConsistencySession() *ConsistencySession {
+	return &ConsistencySession{
+		tokens: make(map[string]map[uint32]*kv_v1.MutationToken),
+	}
+}
+
+type consistencySessionCtxKey struct{}
+
+// WithConsistencySession returns a context which records mutation tokens into, and
+// attaches the recorded tokens to queries from, the given session.
+func WithConsistencySession(ctx context.Context, session *ConsistencySession) context.Context {
+	return context.WithValue(ctx, consistencySessionCtxKey{}, session)
+}
+
+// ConsistencySessionFromContext returns the session set by WithConsistencySession, if any.
+func ConsistencySessionFromContext(ctx context.Context) (*ConsistencySession, bool) {
+	session, ok := ctx.Value(consistencySessionCtxKey{}).(*ConsistencySession)
+	return session, ok && session != nil
+}
+
+// Add records a mutation token, replacing any older token for the same vbucket.
+func (s *ConsistencySession) Add(token *kv_v1.MutationToken) {
+	if token == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	vbuckets, ok := s.tokens[token.BucketName]
+	if !ok {
+		vbuckets = make(map[uint32]*kv_v1.MutationToken)
+		s.tokens[token.BucketName] = vbuckets
+	}
+
+	// A failover changes the vbucket uuid, in which case the newest token always wins.
+	existing, ok := vbuckets[token.VbucketId]
+	if ok && existing.VbucketUuid == token.VbucketUuid && existing.SeqNo >= token.SeqNo {
+		return
+	}
+
+	vbuckets[token.VbucketId] = token
+}
+
+// Tokens returns the latest recorded token for every vbucket, across all buckets.
+func (s *ConsistencySession) Tokens() []*kv_v1.MutationToken {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var tokens []*kv_v1.MutationToken
+	for _, vbuckets := range s.tokens {
+		for _, token := range vbuckets {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].BucketName != tokens[j].BucketName {
+			return tokens[i].BucketName < tokens[j].BucketName
+		}
+		return tokens[i].VbucketId < tokens[j].VbucketId
+	})
+
+	return tokens
+}
+
+// Reset discards all recorded tokens.
+func (s *ConsistencySession) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tokens = make(map[string]map[uint32]*kv_v1.MutationToken)
+}
+
+func recordMutationToken(ctx context.Context, token *kv_v1.MutationToken) {
+	if session, ok := ConsistencySessionFromContext(ctx); ok {
+		session.Add(token)
+	}
+}
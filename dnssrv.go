@@ -0,0 +1,285 @@
+package gocbcoreps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/resolver"
+)
+
+// RoutingScheme is the scheme used for non-optimized routing, addresses are resolved
+// using SRV records where available.
+const RoutingScheme = "couchbase2"
+
+const srvService = "couchbase2"
+
+const srvProto = "tcp"
+
+// DNSResolver performs the DNS lookups used when resolving seed addresses, it is
+// satisfied by *net.Resolver.
+type DNSResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+var _ DNSResolver = (*net.Resolver)(nil)
+
+// resolveSRVAddrs looks up the _couchbase2._tcp SRV records for host, returning
+// their addresses ordered by priority and weight.
+func resolveSRVAddrs(ctx context.Context, dns DNSResolver, host string) ([]string, error) {
+	_, records, err := dns.LookupSRV(ctx, srvService, srvProto, host)
+	if err != nil {
+		return nil, err
+	}
+
+	records = orderSRV(records, nil)
+
+	addrs := make([]string, len(records))
+	for i, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		addrs[i] = net.JoinHostPort(target, strconv.Itoa(int(record.Port)))
+	}
+
+	return addrs, nil
+}
+
+// orderSRV sorts records by ascending priority, and within each priority performs a
+// weighted random ordering as described in RFC 2782. If rng is nil then the global
+// source is used.
+func orderSRV(records []*net.SRV, rng *rand.Rand) []*net.SRV {
+	sorted := append([]*net.SRV(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	for start := 0; start < len(sorted); {
+		end := start + 1
+		for end < len(sorted) && sorted[end].Priority == sorted[start].Priority {
+			end++
+		}
+
+		weightedShuffle(sorted[start:end], rng)
+		start = end
+	}
+
+	return sorted
+}
+
+func weightedShuffle(records []*net.SRV, rng *rand.Rand) {
+	intn := rand.Intn
+	shuffle := rand.Shuffle
+	if rng != nil {
+		intn = rng.Intn
+		shuffle = rng.Shuffle
+	}
+
+	sum := 0
+	for _, record := range records {
+		sum += int(record.Weight)
+	}
+
+	for i := range records {
+		if sum == 0 {
+			// All remaining records have zero weight, so they are equally likely.
+			shuffle(len(records)-i, func(a, b int) {
+				records[i+a], records[i+b] = records[i+b], records[i+a]
+			})
+			return
+		}
+
+		pick := intn(sum + 1)
+		for j := i; j < len(records); j++ {
+			pick -= int(records[j].Weight)
+			if pick <= 0 {
+				records[i], records[j] = records[j], records[i]
+				break
+			}
+		}
+
+		sum -= int(records[i].Weight)
+	}
+}
+
+// SRVResolverBuilder builds resolvers for the couchbase2 scheme which resolve the
+// target using SRV records, periodically refreshing them.
+type SRVResolverBuilder struct {
+	logger          *zap.Logger
+	ctx             context.Context
+	redact          redactor
+	dns             DNSResolver
+	addrMapper      addressMapper
+	resolveInterval time.Duration
+
+	lock      sync.Mutex
+	resolvers map[*srvResolver]struct{}
+}
+
+func (*SRVResolverBuilder) Scheme() string { return RoutingScheme }
+
+func (c *SRVResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, rOpts resolver.BuildOptions) (resolver.Resolver, error) {
+	dns := c.dns
+	if dns == nil {
+		dns = net.DefaultResolver
+	}
+
+	r := &srvResolver{
+		ctx:             c.ctx,
+		logger:          c.logger,
+		redact:          c.redact,
+		dns:             dns,
+		addrMapper:      c.addrMapper,
+		resolveInterval: c.resolveInterval,
+		resolveNow:      make(chan struct{}, 1),
+		done:            make(chan struct{}),
+		onClose:         c.removeResolver,
+		target:          target,
+		cc:              cc,
+	}
+
+	c.lock.Lock()
+	if c.resolvers == nil {
+		c.resolvers = make(map[*srvResolver]struct{})
+	}
+	c.resolvers[r] = struct{}{}
+	c.lock.Unlock()
+
+	go r.watch()
+
+	return r, nil
+}
+
+func (c *SRVResolverBuilder) removeResolver(r *srvResolver) {
+	c.lock.Lock()
+	delete(c.resolvers, r)
+	c.lock.Unlock()
+}
+
+func (c *SRVResolverBuilder) refreshRouting(ctx context.Context) (int, error) {
+	c.lock.Lock()
+	resolvers := make([]*srvResolver, 0, len(c.resolvers))
+	for r := range c.resolvers {
+		resolvers = append(resolvers, r)
+	}
+	c.lock.Unlock()
+
+	var errs []error
+	for _, r := range resolvers {
+		if err := r.refresh(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return len(resolvers), errors.Join(errs...)
+}
+
+type srvResolver struct {
+	ctx             context.Context
+	logger          *zap.Logger
+	redact          redactor
+	dns             DNSResolver
+	addrMapper      addressMapper
+	resolveInterval time.Duration
+	resolveNow      chan struct{}
+	resolveLock     sync.Mutex
+	done            chan struct{}
+	onClose         func(*srvResolver)
+
+	target resolver.Target
+	cc     resolver.ClientConn
+}
+
+func (r *srvResolver) resolve() error {
+	r.resolveLock.Lock()
+	defer r.resolveLock.Unlock()
+
+	addrs, err := resolveAddrs(r.ctx, r.dns, r.target.Endpoint(), r.redact)
+	if err != nil {
+		return err
+	}
+
+	addrs = r.addrMapper.Map(addrs)
+	if len(addrs) == 0 {
+		return fmt.Errorf("no addresses found for '%s'", r.redact.SystemData(r.target.Endpoint()))
+	}
+
+	state := resolver.State{
+		Addresses:     make([]resolver.Address, len(addrs)),
+		ServiceConfig: r.cc.ParseServiceConfig(shuffledPickFirstServiceConfig),
+	}
+	targetHost := strings.Trim(r.target.Endpoint(), "[]")
+	if host, _, err := net.SplitHostPort(r.target.Endpoint()); err == nil {
+		targetHost = host
+	}
+
+	for i, addr := range addrs {
+		host, _, _ := net.SplitHostPort(addr)
+		if net.ParseIP(host) != nil {
+			// The address was resolved from the target hostname so needs to be
+			// verified against that.
+			host = targetHost
+		}
+
+		state.Addresses[i] = resolver.Address{Addr: addr, ServerName: host}
+	}
+
+	return r.cc.UpdateState(state)
+}
+
+func (r *srvResolver) watch() {
+	ticker := time.NewTicker(r.resolveInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.resolve(); err != nil {
+			r.logger.Error("srv resolution failed",
+				r.redact.SystemField("target", r.target.Endpoint()),
+				zap.Error(err))
+			r.cc.ReportError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.resolveNow:
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *srvResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+// refresh performs a resolution immediately, waiting for it to complete.
+func (r *srvResolver) refresh(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- r.resolve()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *srvResolver) Close() {
+	close(r.done)
+	if r.onClose != nil {
+		r.onClose(r)
+	}
+}
@@ -0,0 +1,203 @@
+package gocbcoreps
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"reflect"
+	"testing"
+)
+
+type fakeDNSResolver struct {
+	srv    []*net.SRV
+	srvErr error
+	ips    map[string][]net.IP
+
+	srvLookups []string
+}
+
+func (r *fakeDNSResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.srvLookups = append(r.srvLookups, name)
+	if r.srvErr != nil {
+		return "", nil, r.srvErr
+	}
+	return "", r.srv, nil
+}
+
+func (r *fakeDNSResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	ips, ok := r.ips[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return ips, nil
+}
+
+func TestOrderSRVGroupsByPriority(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "c", Priority: 20, Weight: 10},
+		{Target: "a", Priority: 10, Weight: 0},
+		{Target: "d", Priority: 30, Weight: 5},
+		{Target: "b", Priority: 10, Weight: 50},
+		{Target: "e", Priority: 20, Weight: 0},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		ordered := orderSRV(records, rng)
+		if len(ordered) != len(records) {
+			t.Fatalf("expected %d records, got %d", len(records), len(ordered))
+		}
+
+		for j := 1; j < len(ordered); j++ {
+			if ordered[j].Priority < ordered[j-1].Priority {
+				t.Fatalf("records out of priority order: %v", targets(ordered))
+			}
+		}
+	}
+
+	// The input must not be reordered.
+	if records[0].Target != "c" {
+		t.Fatalf("expected input to be left untouched")
+	}
+}
+
+func TestOrderSRVWeighted(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "light", Priority: 10, Weight: 10},
+		{Target: "heavy", Priority: 10, Weight: 90},
+		{Target: "zero", Priority: 10, Weight: 0},
+	}
+
+	rng := rand.New(rand.NewSource(42))
+	first := make(map[string]int)
+	const runs = 10000
+	for i := 0; i < runs; i++ {
+		first[orderSRV(records, rng)[0].Target]++
+	}
+
+	// Heavy should be picked first roughly 90% of the time, and a zero weight record
+	// only rarely.
+	if first["heavy"] < runs*85/100 || first["heavy"] > runs*95/100 {
+		t.Errorf("expected heavy first ~90%% of the time, got %d/%d", first["heavy"], runs)
+	}
+	if first["zero"] > runs/50 {
+		t.Errorf("expected zero weight record first rarely, got %d/%d", first["zero"], runs)
+	}
+}
+
+func TestOrderSRVDeterministicWithSeed(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "a", Priority: 10, Weight: 30},
+		{Target: "b", Priority: 10, Weight: 30},
+		{Target: "c", Priority: 10, Weight: 40},
+	}
+
+	a := targets(orderSRV(records, rand.New(rand.NewSource(7))))
+	b := targets(orderSRV(records, rand.New(rand.NewSource(7))))
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("expected the same order for the same seed, got %v and %v", a, b)
+	}
+}
+
+func TestOrderSRVAllZeroWeights(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "a", Priority: 10},
+		{Target: "b", Priority: 10},
+		{Target: "c", Priority: 10},
+	}
+
+	ordered := orderSRV(records, rand.New(rand.NewSource(3)))
+
+	seen := make(map[string]bool)
+	for _, record := range ordered {
+		seen[record.Target] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected every record to be kept, got %v", targets(ordered))
+	}
+}
+
+func TestResolveAddrs(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		dns       *fakeDNSResolver
+		expected  []string
+		srvLookup bool
+	}{
+		{
+			name:   "srv records",
+			target: "cb.local",
+			dns: &fakeDNSResolver{
+				srv: []*net.SRV{
+					{Target: "node2.cb.local.", Port: 18098, Priority: 20},
+					{Target: "node1.cb.local.", Port: 18098, Priority: 10},
+				},
+			},
+			expected:  []string{"node1.cb.local:18098", "node2.cb.local:18098"},
+			srvLookup: true,
+		},
+		{
+			name:   "srv lookup fails",
+			target: "cb.local",
+			dns: &fakeDNSResolver{
+				srvErr: errors.New("no srv records"),
+				ips:    map[string][]net.IP{"cb.local": {net.ParseIP("10.0.0.1"), net.ParseIP("::1")}},
+			},
+			expected:  []string{"10.0.0.1:18098", "[::1]:18098"},
+			srvLookup: true,
+		},
+		{
+			name:   "no srv records",
+			target: "cb.local",
+			dns: &fakeDNSResolver{
+				ips: map[string][]net.IP{"cb.local": {net.ParseIP("10.0.0.1")}},
+			},
+			expected:  []string{"10.0.0.1:18098"},
+			srvLookup: true,
+		},
+		{
+			name:   "explicit port skips srv",
+			target: "cb.local:1234",
+			dns: &fakeDNSResolver{
+				srv: []*net.SRV{{Target: "node1.cb.local.", Port: 18098}},
+				ips: map[string][]net.IP{"cb.local": {net.ParseIP("10.0.0.1")}},
+			},
+			expected: []string{"10.0.0.1:1234"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addrs, err := resolveAddrs(context.Background(), test.dns, test.target, redactor{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(addrs, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, addrs)
+			}
+			if (len(test.dns.srvLookups) > 0) != test.srvLookup {
+				t.Fatalf("expected srv lookup %v, got lookups %v", test.srvLookup, test.dns.srvLookups)
+			}
+		})
+	}
+}
+
+func TestResolveAddrsLookupFailure(t *testing.T) {
+	dns := &fakeDNSResolver{srvErr: errors.New("no srv records")}
+
+	_, err := resolveAddrs(context.Background(), dns, "cb.local", redactor{})
+	if err == nil {
+		t.Fatal("expected an error when no addresses can be resolved")
+	}
+}
+
+func targets(records []*net.SRV) []string {
+	out := make([]string, len(records))
+	for i, record := range records {
+		out[i] = record.Target
+	}
+	return out
+}
@@ -1,6 +1,9 @@
 package gocbcoreps
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrAuthenticatorMismatch is returned when there is a mismatch between authenticators.
@@ -8,4 +11,73 @@ var (
 
 	// ErrAuthenticatorUnsupported is returned when a unsupported authenticator is specified.
 	ErrAuthenticatorUnsupported = errors.New("authenticator unsupported")
+
+	// ErrInsecureCredentials is returned when credentials would be sent over a plaintext
+	// connection without AllowInsecureCredentials being specified.
+	ErrInsecureCredentials = errors.New("credentials require transport security")
+
+	// ErrNoSeeds is returned when no seed addresses are specified.
+	ErrNoSeeds = errors.New("no seed addresses specified")
+
+	// ErrInvalidSeed is returned when a seed address cannot be parsed, or a seed with
+	// a scheme is combined with other seeds.
+	ErrInvalidSeed = errors.New("invalid seed address")
+
+	// ErrInvalidNetwork is returned when the selected network is unknown, or requires
+	// an AddressRewriter which was not provided.
+	ErrInvalidNetwork = errors.New("invalid network")
+
+	// ErrUnknownCompressor is returned when compression options name a compressor
+	// which has not been registered.
+	ErrUnknownCompressor = errors.New("unknown compressor")
+
+	// ErrRefreshNotSupported is returned when routing is refreshed but none of the
+	// client's targets have a resolver which can be refreshed.
+	ErrRefreshNotSupported = errors.New("routing refresh not supported for target")
+
+	// ErrInvalidArgument is returned when an operation is called with missing or
+	// invalid arguments.
+	ErrInvalidArgument = errors.New("invalid argument")
+
+	// ErrEncodingFailure is returned when a transcoder is unable to encode a value.
+	ErrEncodingFailure = errors.New("encoding failure")
+
+	// ErrDecodingFailure is returned when a transcoder is unable to decode a document.
+	ErrDecodingFailure = errors.New("decoding failure")
+
+	// ErrNoResult is returned when a single row is requested from a result which has no rows.
+	ErrNoResult = errors.New("no result was available")
+
+	// ErrResultNotFinished is returned when result metadata is requested before all
+	// rows have been read, including when the result was closed early.
+	ErrResultNotFinished = errors.New("result rows have not been fully read")
+
+	// ErrInvalidVector is returned when a vector query contains an empty, malformed or
+	// non-finite vector.
+	ErrInvalidVector = errors.New("invalid vector")
+
+	// ErrFeatureNotAvailable is returned when an option is used which the protocol
+	// cannot express.
+	ErrFeatureNotAvailable = errors.New("feature not available")
+
+	// ErrValueTooLarge is returned when a KV request exceeds the maximum send message size.
+	ErrValueTooLarge = errors.New("value too large")
 )
+
+// ValueTooLargeError is returned, before sending, when a KV request is larger than
+// the configured maximum send message size.
+type ValueTooLargeError struct {
+	Key   string
+	Size  int
+	Limit int
+
+	redactedKey string
+}
+
+func (e *ValueTooLargeError) Error() string {
+	return fmt.Sprintf("value for key '%s' is %d bytes which exceeds the limit of %d bytes", e.redactedKey, e.Size, e.Limit)
+}
+
+func (e *ValueTooLargeError) Unwrap() error {
+	return ErrValueTooLarge
+}
@@ -0,0 +1,98 @@
+package gocbcoreps
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"runtime/debug"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const libraryName = "gocbcoreps"
+
+const libraryModulePath = "github.com/couchbase/gocbcoreps"
+
+const (
+	clientIDMetadataKey   = "cb-client-id"
+	clientNameMetadataKey = "cb-client-name"
+)
+
+var libraryVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	if info.Main.Path == libraryModulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == libraryModulePath {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+
+			return dep.Version
+		}
+	}
+
+	return "unknown"
+})
+
+// clientIdentity identifies this client to the cluster, so that operators are able
+// to attribute load to specific applications.
+type clientIdentity struct {
+	id        string
+	name      string
+	userAgent string
+}
+
+func newClientIdentity(name, userAgent string) clientIdentity {
+	idBytes := make([]byte, 16)
+	// crypto/rand.Read never returns an error on supported platforms.
+	_, _ = rand.Read(idBytes)
+
+	agent := libraryName + "/" + libraryVersion()
+	if name != "" {
+		agent = name + " " + agent
+	}
+	if userAgent != "" {
+		agent = userAgent + " " + agent
+	}
+
+	return clientIdentity{
+		id:        hex.EncodeToString(idBytes),
+		name:      name,
+		userAgent: agent,
+	}
+}
+
+func (i clientIdentity) withMetadata(ctx context.Context) context.Context {
+	if i.name != "" {
+		return metadata.AppendToOutgoingContext(ctx, clientIDMetadataKey, i.id, clientNameMetadataKey, i.name)
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, clientIDMetadataKey, i.id)
+}
+
+// dialOptions returns the user agent, and interceptors which attach our
+// identifying metadata to every RPC.
+func (i clientIdentity) dialOptions() []grpc.DialOption {
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(i.withMetadata(ctx), method, req, reply, cc, opts...)
+	}
+
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(i.withMetadata(ctx), desc, cc, method, opts...)
+	}
+
+	return []grpc.DialOption{
+		grpc.WithUserAgent(i.userAgent),
+		grpc.WithChainUnaryInterceptor(unary),
+		grpc.WithChainStreamInterceptor(stream),
+	}
+}
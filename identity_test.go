@@ -0,0 +1,31 @@
+package gocbcoreps
+
+import "testing"
+
+func TestNewClientIdentityUserAgent(t *testing.T) {
+	library := libraryName + "/" + libraryVersion()
+
+	tests := []struct {
+		name      string
+		client    string
+		userAgent string
+		expected  string
+	}{
+		{name: "default", expected: library},
+		{name: "client name", client: "app", expected: "app " + library},
+		{name: "user agent", userAgent: "ua/1.0", expected: "ua/1.0 " + library},
+		{name: "both", client: "app", userAgent: "ua/1.0", expected: "ua/1.0 app " + library},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			identity := newClientIdentity(test.client, test.userAgent)
+			if identity.userAgent != test.expected {
+				t.Fatalf("expected user agent %q, got %q", test.expected, identity.userAgent)
+			}
+			if len(identity.id) != 32 {
+				t.Fatalf("expected a 128 bit hex id, got %q", identity.id)
+			}
+		})
+	}
+}
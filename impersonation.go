@@ -0,0 +1,143 @@
+package gocbcoreps
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+const onBehalfOfMetadataKey = "cb-on-behalf-of"
+
+const (
+	authenticatedUserSpanAttribute = "db.couchbase.user"
+	impersonatedUserSpanAttribute  = "db.couchbase.impersonated_user"
+)
+
+type impersonatedUserCtxKey struct{}
+
+// WithImpersonatedUser returns a context which causes all RPCs made with it to be
+// performed on behalf of the given user, subject to that user's RBAC.
+func WithImpersonatedUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, impersonatedUserCtxKey{}, user)
+}
+
+// ImpersonatedUserFromContext returns the user set by WithImpersonatedUser, if any.
+func ImpersonatedUserFromContext(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(impersonatedUserCtxKey{}).(string)
+	return user, ok && user != ""
+}
+
+// ImpersonateCallOption is a grpc.CallOption which performs a single RPC on behalf
+// of the given user. It takes precedence over WithImpersonatedUser.
+type ImpersonateCallOption struct {
+	grpc.EmptyCallOption
+	User string
+}
+
+// ImpersonateUser returns a grpc.CallOption which performs the RPC on behalf of user.
+func ImpersonateUser(user string) grpc.CallOption {
+	return ImpersonateCallOption{User: user}
+}
+
+func impersonatedUser(ctx context.Context, opts []grpc.CallOption) string {
+	for _, opt := range opts {
+		if o, ok := opt.(ImpersonateCallOption); ok && o.User != "" {
+			return o.User
+		}
+	}
+
+	user, _ := ImpersonatedUserFromContext(ctx)
+	return user
+}
+
+func withOnBehalfOf(ctx context.Context, user string) context.Context {
+	// The stats handler uses the context value to annotate spans, so make sure
+	// that call options are visible there too.
+	ctx = WithImpersonatedUser(ctx, user)
+	return metadata.AppendToOutgoingContext(ctx, onBehalfOfMetadataKey, user)
+}
+
+// authenticatedUser returns the identity which auth authenticates as, if known.
+func authenticatedUser(auth Authenticator) string {
+	if a, ok := auth.(identifiedAuthenticator); ok {
+		return a.authenticatedUser()
+	}
+
+	return ""
+}
+
+// impersonationDialOptions returns interceptors which attach the on-behalf-of
+// metadata to outgoing RPCs.
+func impersonationDialOptions(logger *zap.Logger, redact redactor, auth Authenticator) []grpc.DialOption {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		user := impersonatedUser(ctx, opts)
+		if user == "" {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		err := invoker(withOnBehalfOf(ctx, user), method, req, reply, cc, opts...)
+		if err != nil {
+			logger.Debug("impersonated request failed",
+				zap.String("method", method),
+				redact.UserField("user", authenticatedUser(auth)),
+				redact.UserField("impersonatedUser", user),
+				zap.Error(err))
+		}
+
+		return err
+	}
+
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		user := impersonatedUser(ctx, opts)
+		if user == "" {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		s, err := streamer(withOnBehalfOf(ctx, user), desc, cc, method, opts...)
+		if err != nil {
+			logger.Debug("impersonated stream failed",
+				zap.String("method", method),
+				redact.UserField("user", authenticatedUser(auth)),
+				redact.UserField("impersonatedUser", user),
+				zap.Error(err))
+		}
+
+		return s, err
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(unary),
+		grpc.WithChainStreamInterceptor(stream),
+	}
+}
+
+// impersonationStatsHandler wraps another stats.Handler, recording both the
+// authenticated and impersonated users on the span created for each impersonated
+// RPC, so that either can be audited.
+type impersonationStatsHandler struct {
+	stats.Handler
+	auth   Authenticator
+	redact redactor
+}
+
+func (h impersonationStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	ctx = h.Handler.TagRPC(ctx, info)
+
+	if user, ok := ImpersonatedUserFromContext(ctx); ok {
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.String(authenticatedUserSpanAttribute, h.redact.UserData(authenticatedUser(h.auth))),
+			attribute.String(impersonatedUserSpanAttribute, h.redact.UserData(user)),
+		)
+	}
+
+	return ctx
+}
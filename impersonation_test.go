@@ -0,0 +1,88 @@
+package gocbcoreps
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/stats"
+)
+
+type recordingSpan struct {
+	noop.Span
+	attrs map[attribute.Key]string
+}
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value.AsString()
+	}
+}
+
+type spanStatsHandler struct {
+	stats.Handler
+	span trace.Span
+}
+
+func (h spanStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return trace.ContextWithSpan(ctx, h.span)
+}
+
+func TestImpersonationStatsHandlerRecordsBothUsers(t *testing.T) {
+	tests := []struct {
+		name          string
+		level         RedactionLevel
+		authenticated string
+		impersonated  string
+	}{
+		{
+			name:          "unredacted",
+			level:         RedactNone,
+			authenticated: "admin",
+			impersonated:  "alice",
+		},
+		{
+			name:          "redacted",
+			level:         RedactPartial,
+			authenticated: "<ud>admin</ud>",
+			impersonated:  "<ud>alice</ud>",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			span := &recordingSpan{attrs: make(map[attribute.Key]string)}
+			handler := impersonationStatsHandler{
+				Handler: spanStatsHandler{span: span},
+				auth:    NewBasicAuthenticator("admin", "password"),
+				redact:  redactor{level: test.level},
+			}
+
+			ctx := WithImpersonatedUser(context.Background(), "alice")
+			handler.TagRPC(ctx, &stats.RPCTagInfo{FullMethodName: "/test"})
+
+			if got := span.attrs[authenticatedUserSpanAttribute]; got != test.authenticated {
+				t.Errorf("expected authenticated user %q, got %q", test.authenticated, got)
+			}
+			if got := span.attrs[impersonatedUserSpanAttribute]; got != test.impersonated {
+				t.Errorf("expected impersonated user %q, got %q", test.impersonated, got)
+			}
+		})
+	}
+}
+
+func TestImpersonationStatsHandlerSkipsNonImpersonatedRPCs(t *testing.T) {
+	span := &recordingSpan{attrs: make(map[attribute.Key]string)}
+	handler := impersonationStatsHandler{
+		Handler: spanStatsHandler{span: span},
+		auth:    NewBasicAuthenticator("admin", "password"),
+	}
+
+	handler.TagRPC(context.Background(), &stats.RPCTagInfo{FullMethodName: "/test"})
+
+	if len(span.attrs) != 0 {
+		t.Fatalf("expected no attributes, got %v", span.attrs)
+	}
+}
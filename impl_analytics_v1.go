@@ -14,5 +14,11 @@ type routingImpl_AnalyticsV1 struct {
 var _ analytics_v1.AnalyticsServiceClient = (*routingImpl_AnalyticsV1)(nil)
 
 func (c *routingImpl_AnalyticsV1) AnalyticsQuery(ctx context.Context, in *analytics_v1.AnalyticsQueryRequest, opts ...grpc.CallOption) (analytics_v1.AnalyticsService_AnalyticsQueryClient, error) {
+	if in.AnalyticsScopeName != nil {
+		if bucketName, ok := analyticsScopeBucket(*in.AnalyticsScopeName); ok {
+			return c.client.fetchConnForBucket(bucketName).AnalyticsV1().AnalyticsQuery(ctx, in, opts...)
+		}
+	}
+
 	return c.client.fetchConn().AnalyticsV1().AnalyticsQuery(ctx, in, opts...)
 }
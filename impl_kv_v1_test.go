@@ -0,0 +1,64 @@
+package gocbcoreps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/couchbase/goprotostellar/genproto/kv_v1"
+	"google.golang.org/grpc"
+)
+
+func TestCheckRequestSize(t *testing.T) {
+	req := &kv_v1.UpsertRequest{
+		Key:     "key",
+		Content: &kv_v1.UpsertRequest_ContentUncompressed{ContentUncompressed: make([]byte, 100)},
+	}
+
+	tests := []struct {
+		name        string
+		maxSend     int
+		defaultOpts []grpc.CallOption
+		callOpts    []grpc.CallOption
+		wantErr     bool
+	}{
+		{name: "unlimited"},
+		{name: "option within limit", maxSend: 1024},
+		{name: "option exceeded", maxSend: 50, wantErr: true},
+		{
+			name:        "default call option exceeded",
+			defaultOpts: []grpc.CallOption{grpc.MaxCallSendMsgSize(50)},
+			wantErr:     true,
+		},
+		{
+			name:        "default call option overrides option",
+			maxSend:     1024,
+			defaultOpts: []grpc.CallOption{grpc.MaxCallSendMsgSize(50)},
+			wantErr:     true,
+		},
+		{
+			name:     "call option overrides default",
+			maxSend:  50,
+			callOpts: []grpc.CallOption{grpc.MaxCallSendMsgSize(1024)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv := &routingImpl_KvV1{client: &RoutingClient{
+				maxSendMsgSize: sendMsgSizeLimit(tt.maxSend, tt.defaultOpts),
+			}}
+
+			err := kv.checkRequestSize(req.Key, req, tt.callOpts)
+			if tt.wantErr {
+				var tooLarge *ValueTooLargeError
+				if !errors.As(err, &tooLarge) || !errors.Is(err, ErrValueTooLarge) {
+					t.Fatalf("expected ValueTooLargeError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
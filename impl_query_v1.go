@@ -5,6 +5,7 @@ import (
 
 	"github.com/couchbase/goprotostellar/genproto/query_v1"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
 type routingImpl_QueryV1 struct {
@@ -15,9 +16,30 @@ type routingImpl_QueryV1 struct {
 var _ query_v1.QueryServiceClient = (*routingImpl_QueryV1)(nil)
 
 func (c *routingImpl_QueryV1) Query(ctx context.Context, in *query_v1.QueryRequest, opts ...grpc.CallOption) (query_v1.QueryService_QueryClient, error) {
+	in = withSessionConsistency(ctx, in)
+
 	if in.BucketName != nil {
 		return c.client.fetchConnForBucket(*in.BucketName).QueryV1().Query(ctx, in, opts...)
 	} else {
 		return c.client.fetchConn().QueryV1().Query(ctx, in, opts...)
 	}
 }
+
+// withSessionConsistency attaches the tokens recorded by the context's consistency
+// session, unless the request already specifies its own consistency requirements.
+func withSessionConsistency(ctx context.Context, in *query_v1.QueryRequest) *query_v1.QueryRequest {
+	session, ok := ConsistencySessionFromContext(ctx)
+	if !ok || in.ScanConsistency != nil || len(in.ConsistentWith) > 0 {
+		return in
+	}
+
+	tokens := session.Tokens()
+	if len(tokens) == 0 {
+		return in
+	}
+
+	// Copy the request rather than modifying the one owned by the caller.
+	req := proto.Clone(in).(*query_v1.QueryRequest)
+	req.ConsistentWith = tokens
+	return req
+}
@@ -15,6 +15,8 @@ type routingImpl_SearchV1 struct {
 var _ search_v1.SearchServiceClient = (*routingImpl_SearchV1)(nil)
 
 func (c *routingImpl_SearchV1) SearchQuery(ctx context.Context, in *search_v1.SearchQueryRequest, opts ...grpc.CallOption) (search_v1.SearchService_SearchQueryClient, error) {
+	// The search protocol does not carry a consistency vector, so any consistency
+	// session on the context is not applied, see ConsistencySession.
 	if in.BucketName != nil {
 		return c.client.fetchConnForBucket(*in.BucketName).SearchV1().SearchQuery(ctx, in, opts...)
 	}
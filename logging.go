@@ -0,0 +1,108 @@
+package gocbcoreps
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newLogger builds the logger used internally from the options provided, combining
+// a zap logger and a slog handler if both are specified.
+func newLogger(zapLogger *zap.Logger, handler slog.Handler) *zap.Logger {
+	if handler == nil {
+		if zapLogger == nil {
+			return zap.NewNop()
+		}
+
+		return zapLogger
+	}
+
+	core := zapcore.Core(&slogCore{handler: handler})
+	if zapLogger != nil {
+		core = zapcore.NewTee(zapLogger.Core(), core)
+	}
+
+	return zap.New(core)
+}
+
+// slogCore is a zapcore.Core which writes entries to a slog.Handler.
+type slogCore struct {
+	handler slog.Handler
+}
+
+var _ zapcore.Core = (*slogCore)(nil)
+
+func (c *slogCore) Enabled(level zapcore.Level) bool {
+	return c.handler.Enabled(context.Background(), slogLevel(level))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	return &slogCore{
+		handler: c.handler.WithAttrs(slogAttrs(fields)),
+	}
+}
+
+func (c *slogCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *slogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	record := slog.NewRecord(entry.Time, slogLevel(entry.Level), entry.Message, 0)
+	if entry.LoggerName != "" {
+		record.AddAttrs(slog.String("logger", entry.LoggerName))
+	}
+	record.AddAttrs(slogAttrs(fields)...)
+
+	return c.handler.Handle(context.Background(), record)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+func slogLevel(level zapcore.Level) slog.Level {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case level == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case level == zapcore.WarnLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// slogAttrs converts fields in order, so that log output is stable. Each field is
+// encoded on its own as a single field can expand to several keys.
+func slogAttrs(fields []zapcore.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, field := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		field.AddTo(enc)
+
+		if value, ok := enc.Fields[field.Key]; ok && len(enc.Fields) == 1 {
+			attrs = append(attrs, slog.Any(field.Key, value))
+			continue
+		}
+
+		keys := make([]string, 0, len(enc.Fields))
+		for key := range enc.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			attrs = append(attrs, slog.Any(key, enc.Fields[key]))
+		}
+	}
+
+	return attrs
+}
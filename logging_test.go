@@ -0,0 +1,43 @@
+package gocbcoreps
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSlogAttrsPreserveFieldOrder(t *testing.T) {
+	fields := []zap.Field{
+		zap.String("z", "1"),
+		zap.Int("a", 2),
+		zap.Bool("m", true),
+		zap.String("b", "4"),
+	}
+
+	for i := 0; i < 20; i++ {
+		attrs := slogAttrs(fields)
+
+		var keys []string
+		for _, attr := range attrs {
+			keys = append(keys, attr.Key)
+		}
+		if got := strings.Join(keys, ","); got != "z,a,m,b" {
+			t.Fatalf("expected attributes in field order, got %s", got)
+		}
+	}
+}
+
+func TestNewLoggerWritesToSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(nil, slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	logger.With(zap.String("conn", "c1")).Info("connected", zap.String("z", "1"), zap.String("a", "2"))
+
+	out := buf.String()
+	if !strings.Contains(out, "msg=connected conn=c1 z=1 a=2") {
+		t.Fatalf("unexpected log output %q", out)
+	}
+}
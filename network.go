@@ -0,0 +1,78 @@
+package gocbcoreps
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// NetworkType specifies which addresses are used to connect to the cluster.
+type NetworkType string
+
+const (
+	// NetworkAuto applies the AddressRewriter to any address it knows about, using
+	// the address as resolved otherwise. This is the default.
+	NetworkAuto NetworkType = "auto"
+
+	// NetworkDefault always uses addresses as they were resolved, the
+	// AddressRewriter is not applied.
+	NetworkDefault NetworkType = "default"
+
+	// NetworkExternal requires every address to be rewritten by the
+	// AddressRewriter, addresses it does not know about are not connected to.
+	NetworkExternal NetworkType = "external"
+)
+
+// AddressRewriter maps an address learned by the resolver, such as an internal
+// Kubernetes address, to the address which should be connected to. It returns
+// false if it has no mapping for the address.
+type AddressRewriter func(addr string) (string, bool)
+
+// addressMapper applies the network configuration to resolved addresses.
+type addressMapper struct {
+	network NetworkType
+	rewrite AddressRewriter
+	logger  *zap.Logger
+	redact  redactor
+}
+
+// validate checks that the network can be honoured, NetworkExternal cannot be used
+// without an AddressRewriter to provide the external addresses.
+func (m addressMapper) validate() error {
+	switch m.network {
+	case "", NetworkAuto, NetworkDefault:
+		return nil
+	case NetworkExternal:
+		if m.rewrite == nil {
+			return fmt.Errorf("%w: %s requires an AddressRewriter", ErrInvalidNetwork, m.network)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: unknown network %q", ErrInvalidNetwork, m.network)
+}
+
+func (m addressMapper) Map(addrs []string) []string {
+	if m.rewrite == nil || m.network == NetworkDefault {
+		return addrs
+	}
+
+	mapped := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		newAddr, ok := m.rewrite(addr)
+		if ok {
+			mapped = append(mapped, newAddr)
+			continue
+		}
+
+		if m.network == NetworkExternal {
+			m.logger.Warn("no external address found, skipping",
+				m.redact.SystemField("address", addr))
+			continue
+		}
+
+		mapped = append(mapped, addr)
+	}
+
+	return mapped
+}
@@ -0,0 +1,78 @@
+package gocbcoreps
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestAddressMapperValidate(t *testing.T) {
+	rewrite := func(addr string) (string, bool) { return addr, true }
+
+	tests := []struct {
+		name    string
+		network NetworkType
+		rewrite AddressRewriter
+		err     error
+	}{
+		{name: "unset"},
+		{name: "auto without rewriter", network: NetworkAuto},
+		{name: "default without rewriter", network: NetworkDefault},
+		{name: "external with rewriter", network: NetworkExternal, rewrite: rewrite},
+		{name: "external without rewriter", network: NetworkExternal, err: ErrInvalidNetwork},
+		{name: "unknown network", network: "internal", rewrite: rewrite, err: ErrInvalidNetwork},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := addressMapper{network: test.network, rewrite: test.rewrite}.validate()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestAddressMapperMap(t *testing.T) {
+	rewrite := func(addr string) (string, bool) {
+		if strings.HasPrefix(addr, "internal") {
+			return "external" + strings.TrimPrefix(addr, "internal"), true
+		}
+		return "", false
+	}
+	addrs := []string{"internal1:18098", "other:18098"}
+
+	tests := []struct {
+		network  NetworkType
+		rewrite  AddressRewriter
+		expected []string
+	}{
+		{network: NetworkAuto, expected: addrs},
+		{network: NetworkAuto, rewrite: rewrite, expected: []string{"external1:18098", "other:18098"}},
+		{network: NetworkDefault, rewrite: rewrite, expected: addrs},
+		{network: NetworkExternal, rewrite: rewrite, expected: []string{"external1:18098"}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.network), func(t *testing.T) {
+			m := addressMapper{network: test.network, rewrite: test.rewrite, logger: zap.NewNop()}
+			mapped := m.Map(addrs)
+			if !reflect.DeepEqual(mapped, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, mapped)
+			}
+		})
+	}
+}
+
+func TestDialSeedsExternalNetworkRequiresRewriter(t *testing.T) {
+	_, err := DialSeeds([]string{"cb.local"}, &DialOptions{
+		InsecureTransport: true,
+		Network:           NetworkExternal,
+	})
+	if !errors.Is(err, ErrInvalidNetwork) {
+		t.Fatalf("expected ErrInvalidNetwork, got %v", err)
+	}
+}
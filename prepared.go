@@ -0,0 +1,96 @@
+package gocbcoreps
+
+import (
+	"container/list"
+	"sync"
+)
+
+const defaultPreparedStatementCacheSize = 5000
+
+// PreparedStatementStats describes the usage of a client's prepared statement cache.
+type PreparedStatementStats struct {
+	Entries   int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// preparedStatementCache tracks the statements which have been sent as prepared,
+// evicting the least recently used once full. A miss means the statement is being
+// prepared for the first time by this client.
+type preparedStatementCache struct {
+	lock      sync.Mutex
+	size      int
+	entries   map[string]*list.Element
+	order     *list.List
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func newPreparedStatementCache(size int) *preparedStatementCache {
+	if size <= 0 {
+		size = defaultPreparedStatementCacheSize
+	}
+
+	return &preparedStatementCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// use records a use of statement, returning whether it was already cached.
+func (c *preparedStatementCache) use(statement string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[statement]; ok {
+		c.hits++
+		c.order.MoveToFront(elem)
+		return true
+	}
+
+	c.misses++
+	c.entries[statement] = c.order.PushFront(statement)
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(string))
+		c.evictions++
+	}
+
+	return false
+}
+
+// remove forgets statement, so that its next use is treated as a miss.
+func (c *preparedStatementCache) remove(statement string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[statement]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, statement)
+	}
+}
+
+func (c *preparedStatementCache) clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+func (c *preparedStatementCache) stats() PreparedStatementStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return PreparedStatementStats{
+		Entries:   c.order.Len(),
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
@@ -0,0 +1,82 @@
+package gocbcoreps
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/couchbase/goprotostellar/genproto/query_v1"
+	"google.golang.org/grpc"
+)
+
+// fakeQueryClient returns an empty result for every query, or err if set.
+type fakeQueryClient struct {
+	query_v1.QueryServiceClient
+
+	err error
+}
+
+func (c *fakeQueryClient) Query(ctx context.Context, in *query_v1.QueryRequest, opts ...grpc.CallOption) (query_v1.QueryService_QueryClient, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return newFakeQueryStream(), nil
+}
+
+func TestPreparedStatementCacheHits(t *testing.T) {
+	cache := newPreparedStatementCache(0)
+	query := &fakeQueryClient{}
+	prepared := &QueryOptions{Prepared: true}
+
+	for _, opts := range []*QueryOptions{prepared, prepared, {}} {
+		req, err := opts.toRequest("SELECT 1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		res, err := executeQueryWith(context.Background(), query, cache, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Close()
+	}
+
+	// The adhoc query is not tracked.
+	stats := cache.stats()
+	if stats != (PreparedStatementStats{Entries: 1, Hits: 1, Misses: 1}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	cache.clear()
+	if stats := cache.stats(); stats.Entries != 0 {
+		t.Fatalf("expected clear to remove entries, got %+v", stats)
+	}
+}
+
+func TestPreparedStatementCacheForgetsFailedStatements(t *testing.T) {
+	cache := newPreparedStatementCache(0)
+	query := &fakeQueryClient{err: errors.New("unavailable")}
+
+	req, _ := (&QueryOptions{Prepared: true}).toRequest("SELECT 1")
+	if _, err := executeQueryWith(context.Background(), query, cache, req); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	if stats := cache.stats(); stats.Entries != 0 || stats.Misses != 1 {
+		t.Fatalf("expected the failed statement to be forgotten, got %+v", stats)
+	}
+}
+
+func TestPreparedStatementCacheEvicts(t *testing.T) {
+	cache := newPreparedStatementCache(2)
+	for _, statement := range []string{"a", "b", "a", "c", "b"} {
+		cache.use(statement)
+	}
+
+	// b is evicted by c, as a was used more recently, and then a is evicted by b.
+	stats := cache.stats()
+	if stats != (PreparedStatementStats{Entries: 2, Hits: 1, Misses: 4, Evictions: 2}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
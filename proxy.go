@@ -0,0 +1,157 @@
+package gocbcoreps
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ContextDialer dials a connection to addr, it is used for every connection made
+// to the cluster.
+type ContextDialer func(ctx context.Context, addr string) (net.Conn, error)
+
+func defaultContextDialer(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// newProxyDialer returns a dialer which connects through the proxy described by
+// proxyURL, using forward to connect to the proxy itself. Supported schemes are
+// http (HTTP CONNECT) and socks5, credentials can be specified in the URL.
+func newProxyDialer(proxyURL string, forward ContextDialer) (ContextDialer, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing proxy url: %w", err)
+	}
+
+	if forward == nil {
+		forward = defaultContextDialer
+	}
+
+	switch u.Scheme {
+	case "http":
+		return httpConnectDialer(u, forward), nil
+	case "socks5", "socks5h":
+		return socks5Dialer(u, forward)
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s'", u.Scheme)
+	}
+}
+
+func httpConnectDialer(u *url.URL, forward ContextDialer) ContextDialer {
+	proxyAddr := u.Host
+	if u.Port() == "" {
+		proxyAddr = net.JoinHostPort(u.Hostname(), "80")
+	}
+
+	var proxyAuth string
+	if u.User != nil {
+		password, _ := u.User.Password()
+		proxyAuth = "Basic " + base64.StdEncoding.EncodeToString([]byte(u.User.Username()+":"+password))
+	}
+
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		conn, err := forward(ctx, proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("dialing proxy: %w", err)
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: make(http.Header),
+		}
+		if proxyAuth != "" {
+			req.Header.Set("Proxy-Authorization", proxyAuth)
+		}
+
+		if err := req.Write(conn); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("writing proxy connect request: %w", err)
+		}
+
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, req)
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("reading proxy connect response: %w", err)
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			_ = conn.Close()
+			return nil, fmt.Errorf("proxy connect failed: %s", resp.Status)
+		}
+
+		_ = conn.SetDeadline(time.Time{})
+
+		if br.Buffered() > 0 {
+			// The proxy has already sent us data from the remote end.
+			return &bufferedConn{Conn: conn, r: br}, nil
+		}
+
+		return conn, nil
+	}
+}
+
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func socks5Dialer(u *url.URL, forward ContextDialer) (ContextDialer, error) {
+	var auth *proxy.Auth
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = &proxy.Auth{
+			User:     u.User.Username(),
+			Password: password,
+		}
+	}
+
+	proxyAddr := u.Host
+	if u.Port() == "" {
+		proxyAddr = net.JoinHostPort(u.Hostname(), "1080")
+	}
+
+	d, err := proxy.SOCKS5("tcp", proxyAddr, auth, forwardDialer(forward))
+	if err != nil {
+		return nil, fmt.Errorf("creating socks5 dialer: %w", err)
+	}
+
+	ctxDialer, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("socks5 dialer does not support contexts")
+	}
+
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		return ctxDialer.DialContext(ctx, "tcp", addr)
+	}, nil
+}
+
+// forwardDialer adapts a ContextDialer for use as a proxy.Dialer.
+type forwardDialer ContextDialer
+
+func (d forwardDialer) Dial(network, addr string) (net.Conn, error) {
+	return d(context.Background(), addr)
+}
+
+func (d forwardDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d(ctx, addr)
+}
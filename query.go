@@ -0,0 +1,160 @@
+package gocbcoreps
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"time"
+
+	"github.com/couchbase/goprotostellar/genproto/query_v1"
+)
+
+// QueryResult iterates over the rows of a query, rows must be fully read before its
+// metadata becomes available. Closing a result returned by Cluster.Query or
+// Scope.Query early abandons the remaining rows, and with them the metadata.
+type QueryResult struct {
+	jsonRowStream
+
+	meta *query_v1.QueryResponse_MetaData
+}
+
+// NewQueryResult wraps a stream returned by QueryV1().Query. Closing the result
+// before all rows are read drains the stream, cancel the context used to create the
+// stream to abandon it instead.
+func NewQueryResult(stream query_v1.QueryService_QueryClient) *QueryResult {
+	return newQueryResult(stream, nil)
+}
+
+func newQueryResult(stream query_v1.QueryService_QueryClient, cancel context.CancelFunc) *QueryResult {
+	r := &QueryResult{}
+	r.cancel = cancel
+	r.recv = func() ([][]byte, error) {
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.MetaData != nil {
+			r.meta = resp.MetaData
+		}
+
+		return resp.Rows, nil
+	}
+
+	return r
+}
+
+// Next advances to the next row, returning false once there are no more rows or an
+// error occurred, in which case Err returns the error.
+func (r *QueryResult) Next() bool {
+	return r.next()
+}
+
+// Row decodes the current row into valuePtr.
+func (r *QueryResult) Row(valuePtr interface{}) error {
+	return r.decodeRow(valuePtr)
+}
+
+// One decodes the first row into valuePtr and closes the result.
+func (r *QueryResult) One(valuePtr interface{}) error {
+	return r.oneRow(valuePtr)
+}
+
+// Err returns any error which occurred during iteration.
+func (r *QueryResult) Err() error {
+	return r.err
+}
+
+// Close stops iteration and releases the underlying stream.
+func (r *QueryResult) Close() error {
+	return r.close()
+}
+
+type QueryWarning struct {
+	Code    uint32
+	Message string
+}
+
+type QueryMetrics struct {
+	ElapsedTime   time.Duration
+	ExecutionTime time.Duration
+	ResultCount   uint64
+	ResultSize    uint64
+	MutationCount uint64
+	SortCount     uint64
+	ErrorCount    uint64
+	WarningCount  uint64
+}
+
+type QueryMetaData struct {
+	RequestID       string
+	ClientContextID string
+	Status          query_v1.QueryResponse_MetaData_Status
+	Metrics         *QueryMetrics
+	Warnings        []QueryWarning
+	Profile         json.RawMessage
+	Signature       json.RawMessage
+}
+
+// MetaData returns the metadata of the query, which is only available once all rows
+// have been read. ErrResultNotFinished is returned if the result was closed before
+// then without being drained.
+func (r *QueryResult) MetaData() (*QueryMetaData, error) {
+	if !r.finished {
+		return nil, ErrResultNotFinished
+	}
+	if r.meta == nil {
+		return nil, ErrNoResult
+	}
+
+	meta := &QueryMetaData{
+		RequestID:       r.meta.RequestId,
+		ClientContextID: r.meta.ClientContextId,
+		Status:          r.meta.Status,
+		Profile:         r.meta.Profile,
+		Signature:       r.meta.Signature,
+	}
+
+	for _, warning := range r.meta.Warnings {
+		meta.Warnings = append(meta.Warnings, QueryWarning{
+			Code:    warning.Code,
+			Message: warning.Message,
+		})
+	}
+
+	if metrics := r.meta.Metrics; metrics != nil {
+		meta.Metrics = &QueryMetrics{
+			ElapsedTime:   metrics.ElapsedTime.AsDuration(),
+			ExecutionTime: metrics.ExecutionTime.AsDuration(),
+			ResultCount:   metrics.ResultCount,
+			ResultSize:    metrics.ResultSize,
+			MutationCount: metrics.MutationCount,
+			SortCount:     metrics.SortCount,
+			ErrorCount:    metrics.ErrorCount,
+			WarningCount:  metrics.WarningCount,
+		}
+	}
+
+	return meta, nil
+}
+
+// QueryRows returns an iterator which decodes each row of the result into a T. The
+// result is closed when iteration stops, any stream error is yielded last.
+func QueryRows[T any](r *QueryResult) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer r.Close()
+
+		for r.Next() {
+			var value T
+			err := r.Row(&value)
+			if !yield(value, err) {
+				return
+			}
+		}
+
+		if err := r.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package gocbcoreps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/couchbase/goprotostellar/genproto/query_v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type QueryOptions struct {
+	ScanConsistency *query_v1.QueryRequest_ScanConsistency
+	ReadOnly        bool
+	ClientContextID string
+	FlexIndex       bool
+	PreserveExpiry  bool
+	ProfileMode     *query_v1.QueryRequest_ProfileMode
+
+	// Prepared asks the server to prepare the statement once and reuse the plan for
+	// later requests with the same statement, and tracks it in the client's prepared
+	// statement cache. By default statements are executed adhoc.
+	Prepared bool
+
+	// NamedParameters are JSON encoded, names without a leading $ have one added.
+	NamedParameters map[string]any
+
+	// PositionalParameters are JSON encoded and bound to $1, $2 and so on.
+	PositionalParameters []any
+
+	MaxParallelism uint32
+	PipelineBatch  uint32
+	PipelineCap    uint32
+	ScanCap        uint32
+	ScanWait       time.Duration
+	DisableMetrics bool
+}
+
+func (o *QueryOptions) toRequest(statement string) (*query_v1.QueryRequest, error) {
+	req := &query_v1.QueryRequest{
+		Statement:       statement,
+		ScanConsistency: o.ScanConsistency,
+		ProfileMode:     o.ProfileMode,
+	}
+
+	if o.ReadOnly {
+		req.ReadOnly = &o.ReadOnly
+	}
+	if o.ClientContextID != "" {
+		req.ClientContextId = &o.ClientContextID
+	}
+	if o.FlexIndex {
+		req.FlexIndex = &o.FlexIndex
+	}
+	if o.PreserveExpiry {
+		req.PreserveExpiry = &o.PreserveExpiry
+	}
+
+	if o.Prepared {
+		req.Prepared = &o.Prepared
+	}
+
+	var err error
+	req.NamedParameters, err = encodeNamedParameters(o.NamedParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	req.PositionalParameters, err = encodePositionalParameters(o.PositionalParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	tuning := &query_v1.QueryRequest_TuningOptions{}
+	hasTuning := false
+	if o.MaxParallelism > 0 {
+		tuning.MaxParallelism = &o.MaxParallelism
+		hasTuning = true
+	}
+	if o.PipelineBatch > 0 {
+		tuning.PipelineBatch = &o.PipelineBatch
+		hasTuning = true
+	}
+	if o.PipelineCap > 0 {
+		tuning.PipelineCap = &o.PipelineCap
+		hasTuning = true
+	}
+	if o.ScanCap > 0 {
+		tuning.ScanCap = &o.ScanCap
+		hasTuning = true
+	}
+	if o.ScanWait > 0 {
+		tuning.ScanWait = durationpb.New(o.ScanWait)
+		hasTuning = true
+	}
+	if o.DisableMetrics {
+		tuning.DisableMetrics = &o.DisableMetrics
+		hasTuning = true
+	}
+	if hasTuning {
+		req.TuningOptions = tuning
+	}
+
+	return req, nil
+}
+
+func encodeNamedParameters(params map[string]any) (map[string][]byte, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	encoded := make(map[string][]byte, len(params))
+	for name, value := range params {
+		content, err := encodeJSONValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("encoding named parameter '%s': %w", name, err)
+		}
+
+		if !strings.HasPrefix(name, "$") {
+			name = "$" + name
+		}
+		encoded[name] = content
+	}
+
+	return encoded, nil
+}
+
+func encodePositionalParameters(params []any) ([][]byte, error) {
+	var encoded [][]byte
+	for i, value := range params {
+		content, err := encodeJSONValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("encoding positional parameter %d: %w", i+1, err)
+		}
+
+		encoded = append(encoded, content)
+	}
+
+	return encoded, nil
+}
+
+func executeQuery(ctx context.Context, client *RoutingClient, req *query_v1.QueryRequest) (*QueryResult, error) {
+	return executeQueryWith(ctx, client.QueryV1(), client.prepared, req)
+}
+
+func executeQueryWith(ctx context.Context, query query_v1.QueryServiceClient, cache *preparedStatementCache, req *query_v1.QueryRequest) (*QueryResult, error) {
+	prepared := req.GetPrepared()
+	if prepared {
+		cache.use(req.Statement)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := query.Query(ctx, req)
+	if err != nil {
+		cancel()
+		if prepared {
+			// The statement may not have been prepared, so its next use is a miss.
+			cache.remove(req.Statement)
+		}
+		return nil, err
+	}
+
+	return newQueryResult(stream, cancel), nil
+}
+
+// Query executes a cluster level query.
+func (c *Cluster) Query(ctx context.Context, statement string, opts *QueryOptions) (*QueryResult, error) {
+	if opts == nil {
+		opts = &QueryOptions{}
+	}
+
+	req, err := opts.toRequest(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return executeQuery(ctx, c.client, req)
+}
+
+// Query executes a query with this scope as its query context.
+func (s *Scope) Query(ctx context.Context, statement string, opts *QueryOptions) (*QueryResult, error) {
+	if opts == nil {
+		opts = &QueryOptions{}
+	}
+
+	req, err := opts.toRequest(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName := s.BucketName()
+	scopeName := s.name
+	req.BucketName = &bucketName
+	req.ScopeName = &scopeName
+
+	return executeQuery(ctx, s.bucket.cluster.client, req)
+}
@@ -0,0 +1,86 @@
+package gocbcoreps
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryOptionsToRequest(t *testing.T) {
+	req, err := (&QueryOptions{}).toRequest("SELECT 1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Statement != "SELECT 1" || req.Prepared != nil {
+		t.Fatalf("expected an adhoc statement by default, got %v", req)
+	}
+	if req.ReadOnly != nil || req.ClientContextId != nil || req.TuningOptions != nil {
+		t.Fatalf("expected unset options to be omitted, got %v", req)
+	}
+
+	req, err = (&QueryOptions{
+		Prepared:        true,
+		ReadOnly:        true,
+		ClientContextID: "ctx",
+		ScanWait:        time.Second,
+		ScanCap:         10,
+	}).toRequest("SELECT 1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !req.GetPrepared() {
+		t.Fatalf("expected statement to be prepared")
+	}
+	if !req.GetReadOnly() || req.GetClientContextId() != "ctx" {
+		t.Fatalf("expected options to be set, got %v", req)
+	}
+	if req.TuningOptions.GetScanWait().AsDuration() != time.Second || req.TuningOptions.GetScanCap() != 10 {
+		t.Fatalf("expected tuning options to be set, got %v", req.TuningOptions)
+	}
+}
+
+func TestEncodeNamedParameters(t *testing.T) {
+	encoded, err := encodeNamedParameters(map[string]any{
+		"name":  "value",
+		"$age":  42,
+		"$list": []int{1, 2},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string][]byte{
+		"$name": []byte(`"value"`),
+		"$age":  []byte(`42`),
+		"$list": []byte(`[1,2]`),
+	}
+	if !reflect.DeepEqual(encoded, expected) {
+		t.Fatalf("expected %s, got %s", expected, encoded)
+	}
+
+	if encoded, err := encodeNamedParameters(nil); encoded != nil || err != nil {
+		t.Fatalf("expected no parameters, got %v, %v", encoded, err)
+	}
+
+	if _, err := encodeNamedParameters(map[string]any{"bad": math.NaN()}); !errors.Is(err, ErrEncodingFailure) {
+		t.Fatalf("expected ErrEncodingFailure, got %v", err)
+	}
+}
+
+func TestEncodePositionalParameters(t *testing.T) {
+	encoded, err := encodePositionalParameters([]any{"a", 1, nil})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][]byte{[]byte(`"a"`), []byte(`1`), []byte(`null`)}
+	if !reflect.DeepEqual(encoded, expected) {
+		t.Fatalf("expected %s, got %s", expected, encoded)
+	}
+
+	if _, err := encodePositionalParameters([]any{1, math.Inf(1)}); !errors.Is(err, ErrEncodingFailure) {
+		t.Fatalf("expected ErrEncodingFailure, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package gocbcoreps
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/couchbase/goprotostellar/genproto/query_v1"
+	"google.golang.org/grpc"
+)
+
+type fakeQueryStream struct {
+	grpc.ClientStream
+
+	resps []*query_v1.QueryResponse
+	err   error
+	recvs int
+}
+
+func (s *fakeQueryStream) Recv() (*query_v1.QueryResponse, error) {
+	s.recvs++
+	if len(s.resps) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+
+	resp := s.resps[0]
+	s.resps = s.resps[1:]
+	return resp, nil
+}
+
+func newFakeQueryStream(batches ...[]string) *fakeQueryStream {
+	s := &fakeQueryStream{}
+	for _, batch := range batches {
+		resp := &query_v1.QueryResponse{}
+		for _, row := range batch {
+			resp.Rows = append(resp.Rows, []byte(row))
+		}
+		s.resps = append(s.resps, resp)
+	}
+	s.resps = append(s.resps, &query_v1.QueryResponse{
+		MetaData: &query_v1.QueryResponse_MetaData{RequestId: "req"},
+	})
+
+	return s
+}
+
+func TestQueryResultIteratesBatches(t *testing.T) {
+	r := NewQueryResult(newFakeQueryStream([]string{`1`, `2`}, nil, []string{`3`}))
+
+	if _, err := r.MetaData(); !errors.Is(err, ErrResultNotFinished) {
+		t.Fatalf("expected ErrResultNotFinished, got %v", err)
+	}
+
+	var rows []int
+	for r.Next() {
+		var row int
+		if err := r.Row(&row); err != nil {
+			t.Fatalf("failed to decode row: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 3 || rows[0] != 1 || rows[1] != 2 || rows[2] != 3 {
+		t.Fatalf("expected rows [1 2 3], got %v", rows)
+	}
+
+	meta, err := r.MetaData()
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	if meta.RequestID != "req" {
+		t.Fatalf("expected request id req, got %q", meta.RequestID)
+	}
+
+	if err := r.Row(new(int)); !errors.Is(err, ErrNoResult) {
+		t.Fatalf("expected ErrNoResult after iteration")
+	}
+}
+
+func TestQueryResultStreamError(t *testing.T) {
+	streamErr := errors.New("stream failed")
+	stream := newFakeQueryStream([]string{`1`})
+	stream.resps = stream.resps[:1]
+	stream.err = streamErr
+
+	r := NewQueryResult(stream)
+	if !r.Next() {
+		t.Fatalf("expected a row before the error")
+	}
+	if r.Next() {
+		t.Fatalf("expected iteration to stop on error")
+	}
+	if !errors.Is(r.Err(), streamErr) {
+		t.Fatalf("expected stream error, got %v", r.Err())
+	}
+	if !errors.Is(r.Close(), streamErr) {
+		t.Fatalf("expected close to return the stream error")
+	}
+}
+
+func TestQueryResultRawRow(t *testing.T) {
+	r := NewQueryResult(newFakeQueryStream([]string{`{"a":1}`}))
+
+	var raw json.RawMessage
+	if err := r.One(&raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(raw) != `{"a":1}` {
+		t.Fatalf("expected raw row, got %s", raw)
+	}
+}
+
+func TestQueryResultOne(t *testing.T) {
+	tests := []struct {
+		name     string
+		batches  [][]string
+		expected int
+		err      error
+	}{
+		{name: "first row", batches: [][]string{{`1`, `2`}, {`3`}}, expected: 1},
+		{name: "no rows", err: ErrNoResult},
+		{name: "decoding failure", batches: [][]string{{`"x"`}}, err: ErrDecodingFailure},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream := newFakeQueryStream(test.batches...)
+			r := NewQueryResult(stream)
+
+			var row int
+			err := r.One(&row)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if row != test.expected {
+				t.Fatalf("expected %d, got %d", test.expected, row)
+			}
+			if len(stream.resps) != 0 {
+				t.Fatalf("expected the stream to be drained")
+			}
+		})
+	}
+}
+
+func TestQueryResultCloseCancels(t *testing.T) {
+	stream := newFakeQueryStream([]string{`1`}, []string{`2`})
+	cancelled := 0
+	r := newQueryResult(stream, func() { cancelled++ })
+
+	if !r.Next() {
+		t.Fatalf("expected a row")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cancelled != 1 {
+		t.Fatalf("expected cancel to be called once, got %d", cancelled)
+	}
+	if stream.recvs != 1 {
+		t.Fatalf("expected close not to drain a cancellable stream, got %d recvs", stream.recvs)
+	}
+	if r.Next() {
+		t.Fatalf("expected no rows after close")
+	}
+}
+
+func TestQueryRows(t *testing.T) {
+	type row struct {
+		A int `json:"a"`
+	}
+
+	streamErr := errors.New("stream failed")
+	stream := newFakeQueryStream([]string{`{"a":1}`, `"bad"`, `{"a":3}`})
+	stream.resps = stream.resps[:1]
+	stream.err = streamErr
+
+	var values []int
+	var errs []error
+	for value, err := range QueryRows[row](NewQueryResult(stream)) {
+		values = append(values, value.A)
+		errs = append(errs, err)
+	}
+
+	if len(values) != 4 {
+		t.Fatalf("expected 3 rows and a stream error, got %d results", len(values))
+	}
+	if errs[0] != nil || values[0] != 1 || errs[2] != nil || values[2] != 3 {
+		t.Fatalf("unexpected rows %v, errors %v", values, errs)
+	}
+	if !errors.Is(errs[1], ErrDecodingFailure) {
+		t.Fatalf("expected a decoding failure for the second row, got %v", errs[1])
+	}
+	if !errors.Is(errs[3], streamErr) {
+		t.Fatalf("expected the stream error last, got %v", errs[3])
+	}
+}
+
+func TestQueryRowsStopEarly(t *testing.T) {
+	stream := newFakeQueryStream([]string{`1`, `2`}, []string{`3`})
+
+	for range QueryRows[int](NewQueryResult(stream)) {
+		break
+	}
+
+	if len(stream.resps) != 0 {
+		t.Fatalf("expected the stream to be drained when iteration stops early")
+	}
+}
+
+func TestQueryResultMetaDataAfterClose(t *testing.T) {
+	t.Run("cancellable", func(t *testing.T) {
+		r := newQueryResult(newFakeQueryStream([]string{`1`}, []string{`2`}), func() {})
+
+		if !r.Next() {
+			t.Fatalf("expected a row")
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := r.MetaData(); !errors.Is(err, ErrResultNotFinished) {
+			t.Fatalf("expected ErrResultNotFinished, got %v", err)
+		}
+	})
+
+	t.Run("drained", func(t *testing.T) {
+		r := NewQueryResult(newFakeQueryStream([]string{`1`}, []string{`2`}))
+
+		if !r.Next() {
+			t.Fatalf("expected a row")
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		meta, err := r.MetaData()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if meta.RequestID != "req" {
+			t.Fatalf("expected request id req, got %q", meta.RequestID)
+		}
+	})
+}
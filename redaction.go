@@ -0,0 +1,73 @@
+package gocbcoreps
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
+
+// RedactionLevel specifies which classes of data are redacted from the logs and
+// errors produced by this library.
+type RedactionLevel uint8
+
+const (
+	// RedactNone performs no redaction.
+	RedactNone RedactionLevel = iota
+
+	// RedactPartial redacts user data, such as document keys, query statements
+	// and usernames.
+	RedactPartial
+
+	// RedactFull redacts user data, meta data (bucket, scope and collection names)
+	// and system data (hostnames and addresses).
+	RedactFull
+)
+
+// redactor tags or hashes sensitive values according to a RedactionLevel. Tagged
+// values can later be scrubbed by Couchbase log redaction tooling.
+type redactor struct {
+	level        RedactionLevel
+	hashUserData bool
+}
+
+func (r redactor) UserData(v string) string {
+	if r.level < RedactPartial {
+		return v
+	}
+
+	if r.hashUserData {
+		h := sha1.Sum([]byte(v))
+		v = hex.EncodeToString(h[:])
+	}
+
+	return "<ud>" + v + "</ud>"
+}
+
+func (r redactor) MetaData(v string) string {
+	if r.level < RedactFull {
+		return v
+	}
+
+	return "<md>" + v + "</md>"
+}
+
+func (r redactor) SystemData(v string) string {
+	if r.level < RedactFull {
+		return v
+	}
+
+	return "<sd>" + v + "</sd>"
+}
+
+func (r redactor) UserField(key, v string) zap.Field {
+	return zap.String(key, r.UserData(v))
+}
+
+func (r redactor) MetaField(key, v string) zap.Field {
+	return zap.String(key, r.MetaData(v))
+}
+
+func (r redactor) SystemField(key, v string) zap.Field {
+	return zap.String(key, r.SystemData(v))
+}
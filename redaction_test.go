@@ -0,0 +1,76 @@
+package gocbcoreps
+
+import "testing"
+
+func TestRedactor(t *testing.T) {
+	// sha1("key")
+	const hashedKey = "a62f2225bf70bfaccbc7f1ef2a397836717377de"
+
+	tests := []struct {
+		name   string
+		redact redactor
+		user   string
+		meta   string
+		system string
+	}{
+		{
+			name:   "none",
+			redact: redactor{level: RedactNone},
+			user:   "key",
+			meta:   "bucket",
+			system: "host",
+		},
+		{
+			name:   "none ignores hashing",
+			redact: redactor{level: RedactNone, hashUserData: true},
+			user:   "key",
+			meta:   "bucket",
+			system: "host",
+		},
+		{
+			name:   "partial",
+			redact: redactor{level: RedactPartial},
+			user:   "<ud>key</ud>",
+			meta:   "bucket",
+			system: "host",
+		},
+		{
+			name:   "partial hashed",
+			redact: redactor{level: RedactPartial, hashUserData: true},
+			user:   "<ud>" + hashedKey + "</ud>",
+			meta:   "bucket",
+			system: "host",
+		},
+		{
+			name:   "full",
+			redact: redactor{level: RedactFull},
+			user:   "<ud>key</ud>",
+			meta:   "<md>bucket</md>",
+			system: "<sd>host</sd>",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if v := test.redact.UserData("key"); v != test.user {
+				t.Errorf("expected user data %q, got %q", test.user, v)
+			}
+			if v := test.redact.MetaData("bucket"); v != test.meta {
+				t.Errorf("expected meta data %q, got %q", test.meta, v)
+			}
+			if v := test.redact.SystemData("host"); v != test.system {
+				t.Errorf("expected system data %q, got %q", test.system, v)
+			}
+
+			if f := test.redact.UserField("k", "key"); f.Key != "k" || f.String != test.user {
+				t.Errorf("expected user field %q, got %q", test.user, f.String)
+			}
+			if f := test.redact.MetaField("k", "bucket"); f.String != test.meta {
+				t.Errorf("expected meta field %q, got %q", test.meta, f.String)
+			}
+			if f := test.redact.SystemField("k", "host"); f.String != test.system {
+				t.Errorf("expected system field %q, got %q", test.system, f.String)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package gocbcoreps
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
@@ -12,6 +13,7 @@ import (
 	"github.com/couchbase/goprotostellar/genproto/routing_v2"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/resolver"
 )
 
@@ -19,35 +21,77 @@ const OptimizedRoutingScheme = "couchbase2+optimized"
 
 const defaultResolveInterval = time.Second * 30
 
+const resolveTimeout = time.Second * 10
+
+const (
+	watchRoutingMinBackoff = time.Millisecond * 100
+	watchRoutingMaxBackoff = time.Second * 10
+)
+
 // TO DO - move this into the load balancing implementation when added.
 const customLBName = "optimized_load_balancer"
 
 type CustomResolverBuilder struct {
-	logger          *zap.Logger
-	ctx             context.Context
-	auth            Authenticator
-	resolveInterval time.Duration
+	logger                   *zap.Logger
+	ctx                      context.Context
+	auth                     Authenticator
+	allowInsecureCredentials bool
+	redact                   redactor
+	dns                      DNSResolver
+	contextDialer            ContextDialer
+	addrMapper               addressMapper
+	identity                 clientIdentity
+	resolveInterval          time.Duration
+
+	lock      sync.Mutex
+	resolvers map[*customResolver]struct{}
 }
 
 func (*CustomResolverBuilder) Scheme() string { return OptimizedRoutingScheme }
 
+func (c *CustomResolverBuilder) dnsResolver() DNSResolver {
+	if c.dns == nil {
+		return net.DefaultResolver
+	}
+
+	return c.dns
+}
+
 func (c *CustomResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, rOpts resolver.BuildOptions) (resolver.Resolver, error) {
 	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(rOpts.DialCreds)}
+	dialOpts = append(dialOpts, c.identity.dialOptions()...)
+	if c.contextDialer != nil {
+		dialOpts = append(dialOpts, grpc.WithContextDialer(c.contextDialer))
+	}
 
 	// If the authenticator in use is cert auth then the tls config is
 	// propagated through the rOpts and set above. If the client is using basic
 	// auth then we need to check for that and add to the dial opts here.
-	basicAuth, _ := c.auth.(*BasicAuthenticator)
-	if basicAuth != nil {
-		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(basicAuth))
+	var perRpcCreds credentials.PerRPCCredentials
+	switch a := c.auth.(type) {
+	case *BasicAuthenticator:
+		perRpcCreds = a
+	case *CredentialProviderAuthenticator:
+		perRpcCreds = a
+		dialOpts = append(dialOpts, credentialRefreshDialOptions(a)...)
+	}
+	if perRpcCreds != nil {
+		if c.allowInsecureCredentials {
+			dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(insecurePerRPCCredentials{perRpcCreds}))
+		} else {
+			dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(perRpcCreds))
+		}
 	}
 
 	r := &customResolver{
-		ctx:             c.ctx,
 		logger:          c.logger,
+		redact:          c.redact,
+		dns:             c.dnsResolver(),
+		addrMapper:      c.addrMapper,
 		done:            make(chan struct{}),
-		resolveNow:      make(chan struct{}),
+		resolveNow:      make(chan struct{}, 1),
 		resolveInterval: c.resolveInterval,
+		onClose:         c.removeResolver,
 
 		target:   target,
 		dialOpts: dialOpts,
@@ -55,27 +99,40 @@ func (c *CustomResolverBuilder) Build(target resolver.Target, cc resolver.Client
 
 		targetToConn:   make(map[string]*grpc.ClientConn),
 		routingInfoMap: make(map[string]*routing_v2.WatchRoutingResponse),
+		watchers:       make(map[string]*routingWatcher),
 		routingUpdates: make(chan routingUpdate),
 	}
+	r.ctx, r.cancel = context.WithCancel(c.ctx)
 
 	// Start a routine to watch for updates from the watch routing streams and
 	// update our map.
 	go func() {
-		for u := range r.routingUpdates {
-			r.shouldUpdate.Store(true)
-
-			r.routingLock.Lock()
-			if u.err != nil {
-				delete(r.routingInfoMap, u.key)
+		for {
+			select {
+			case u := <-r.routingUpdates:
+				r.routingLock.Lock()
+				if u.err != nil {
+					delete(r.routingInfoMap, u.key)
+				} else {
+					r.routingInfoMap[u.key] = u.resp
+				}
 				r.routingLock.Unlock()
-				continue
-			}
 
-			r.routingInfoMap[u.key] = u.resp
-			r.routingLock.Unlock()
+				r.shouldUpdate.Store(true)
+				r.updateState()
+			case <-r.done:
+				return
+			}
 		}
 	}()
 
+	c.lock.Lock()
+	if c.resolvers == nil {
+		c.resolvers = make(map[*customResolver]struct{})
+	}
+	c.resolvers[r] = struct{}{}
+	c.lock.Unlock()
+
 	go r.watch()
 
 	r.ResolveNow(resolver.ResolveNowOptions{})
@@ -83,12 +140,49 @@ func (c *CustomResolverBuilder) Build(target resolver.Target, cc resolver.Client
 	return r, nil
 }
 
+func (c *CustomResolverBuilder) removeResolver(r *customResolver) {
+	c.lock.Lock()
+	delete(c.resolvers, r)
+	c.lock.Unlock()
+}
+
+func (c *CustomResolverBuilder) refreshRouting(ctx context.Context) (int, error) {
+	c.lock.Lock()
+	resolvers := make([]*customResolver, 0, len(c.resolvers))
+	for r := range c.resolvers {
+		resolvers = append(resolvers, r)
+	}
+	c.lock.Unlock()
+
+	var errs []error
+	for _, r := range resolvers {
+		if err := r.refresh(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return len(resolvers), errors.Join(errs...)
+}
+
+// routingRefresher is implemented by resolver builders which are able to force
+// their resolvers to refresh immediately. It returns the number of resolvers which
+// were refreshed.
+type routingRefresher interface {
+	refreshRouting(ctx context.Context) (int, error)
+}
+
 type customResolver struct {
 	ctx             context.Context
+	cancel          context.CancelFunc
 	logger          *zap.Logger
+	redact          redactor
+	dns             DNSResolver
+	addrMapper      addressMapper
 	resolveNow      chan struct{}
+	resolveLock     sync.Mutex
 	done            chan struct{}
 	resolveInterval time.Duration
+	onClose         func(*customResolver)
 
 	target   resolver.Target
 	dialOpts []grpc.DialOption
@@ -96,10 +190,12 @@ type customResolver struct {
 
 	targetToConn   map[string]*grpc.ClientConn
 	routingInfoMap map[string]*routing_v2.WatchRoutingResponse
+	watchers       map[string]*routingWatcher
 	routingLock    sync.Mutex
 	routingUpdates chan routingUpdate
 
 	addrs        []string
+	buckets      []string
 	shouldUpdate atomic.Bool
 }
 
@@ -109,112 +205,279 @@ type routingUpdate struct {
 	err  error
 }
 
+// routingWatcher tracks the routine watching routing for a single bucket on a
+// single address.
+type routingWatcher struct {
+	bucketName string
+	addr       string
+	cancel     context.CancelFunc
+}
+
 func (r *customResolver) resolve() error {
-	addrs, err := resolveAddrs(r.target.Endpoint())
+	r.resolveLock.Lock()
+	defer r.resolveLock.Unlock()
+
+	addrs, err := resolveAddrs(r.ctx, r.dns, r.target.Endpoint(), r.redact)
 	if err != nil {
 		return err
 	}
 
-	eps := make([]resolver.Endpoint, len(addrs))
-	conns := make([]*grpc.ClientConn, len(addrs))
-	for i, addr := range addrs {
-		eps[i] = resolver.Endpoint{
-			Addresses: []resolver.Address{{Addr: addr}},
-		}
+	// Apply any address rewriting before we create connections, so that we are
+	// able to connect across NAT boundaries.
+	addrs = r.addrMapper.Map(addrs)
+
+	r.routingLock.Lock()
+	if r.ctx.Err() != nil {
+		// We've been closed whilst resolving addresses.
+		r.routingLock.Unlock()
+		return r.ctx.Err()
+	}
 
+	conns := make([]*grpc.ClientConn, 0, len(addrs))
+	liveAddrs := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
 		// Try to reuse connections we have already established in previous
 		// resolutions.
 		conn, ok := r.targetToConn[addr]
 		if !ok {
-			// We've found a new address so we need to update our LB state
-			r.shouldUpdate.Store(true)
-
 			conn, err = grpc.NewClient(addr, r.dialOpts...)
 			if err != nil {
-				return fmt.Errorf("failed to create connection for '%s': %w", addr, err)
+				r.logger.Warn("failed to create connection",
+					r.redact.SystemField("address", addr),
+					zap.Error(err))
+				continue
 			}
 
+			// We've found a new address so we need to update our LB state
+			r.shouldUpdate.Store(true)
 			r.targetToConn[addr] = conn
 		}
 
-		conns[i] = conn
+		conns = append(conns, conn)
+		liveAddrs = append(liveAddrs, addr)
 	}
+	r.removeStaleAddrsLocked(liveAddrs)
+	r.addrs = liveAddrs
+	r.routingLock.Unlock()
 
-	bucketClient := admin_bucket_v1.NewBucketAdminServiceClient(conns[0])
-	resp, err := bucketClient.ListBuckets(r.ctx, &admin_bucket_v1.ListBucketsRequest{})
+	if len(conns) == 0 {
+		return fmt.Errorf("failed to create a connection for any address of '%s'", r.redact.SystemData(r.target.Endpoint()))
+	}
+
+	buckets, err := r.listBuckets(conns)
 	if err != nil {
-		return fmt.Errorf("listing buckets: %w", err)
+		// We can still update our state with the addresses we have, and keep
+		// using any routing we already know about.
+		r.updateState()
+		return err
 	}
 
+	r.routingLock.Lock()
+	r.buckets = buckets
+	r.removeStaleBucketsLocked(buckets)
 	for i, conn := range conns {
-		bucketToLocalVBs := make(map[string][]uint32)
-		bucketToNumVBs := make(map[string]uint32)
+		for _, bucketName := range buckets {
+			key := constructKey(bucketName, liveAddrs[i])
+			if _, ok := r.watchers[key]; ok {
+				continue
+			}
 
-		for _, b := range resp.Buckets {
-			r.routingLock.Lock()
-			resp, ok := r.routingInfoMap[constructKey(b.BucketName, conn.Target())]
-			r.routingLock.Unlock()
-			if !ok {
-				// We've found a new bucket so we need to update our LB
-				r.shouldUpdate.Store(true)
+			// We've found a new bucket so we need to update our LB
+			r.shouldUpdate.Store(true)
+			r.startWatcherLocked(key, bucketName, liveAddrs[i], conn)
+		}
+	}
+	r.routingLock.Unlock()
 
-				rClient := routing_v2.NewRoutingServiceClient(conn)
-				rStream, err := rClient.WatchRouting(r.ctx, &routing_v2.WatchRoutingRequest{
-					BucketName: &b.BucketName,
-				})
-				if err != nil {
-					return fmt.Errorf("watch routing request failed: %w", err)
-				}
+	r.updateState()
 
-				resp, err = rStream.Recv()
-				if err != nil {
-					return fmt.Errorf("failed to receive watch routing result: %w", err)
-				}
+	return nil
+}
 
-				// Send update down channel to update the routingInfo map
-				key := constructKey(b.BucketName, conn.Target())
-				r.routingUpdates <- routingUpdate{
-					key:  key,
-					resp: resp,
-				}
+// listBuckets tries each connection in turn until one is able to list buckets.
+func (r *customResolver) listBuckets(conns []*grpc.ClientConn) ([]string, error) {
+	var lastErr error
+	for _, conn := range conns {
+		ctx, cancel := context.WithTimeout(r.ctx, resolveTimeout)
+		bucketClient := admin_bucket_v1.NewBucketAdminServiceClient(conn)
+		resp, err := bucketClient.ListBuckets(ctx, &admin_bucket_v1.ListBucketsRequest{})
+		cancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		buckets := make([]string, len(resp.Buckets))
+		for i, b := range resp.Buckets {
+			buckets[i] = b.BucketName
+		}
+
+		return buckets, nil
+	}
+
+	return nil, fmt.Errorf("listing buckets: %w", lastErr)
+}
+
+// removeStaleAddrsLocked closes the connections, and stops the watchers, for any
+// addresses which are no longer returned by DNS.
+func (r *customResolver) removeStaleAddrsLocked(addrs []string) {
+	live := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		live[addr] = struct{}{}
+	}
+
+	for key, w := range r.watchers {
+		if _, ok := live[w.addr]; !ok {
+			r.stopWatcherLocked(key, w)
+		}
+	}
 
-				// Start a routine to watch for future updates
-				go func(key string) {
-					for {
-						resp, err := rStream.Recv()
-						select {
-						case r.routingUpdates <- routingUpdate{
-							key:  key,
-							resp: resp,
-							err:  err,
-						}:
-							if err != nil {
-								return
-							}
-						case <-r.done:
-							return
-						}
-					}
-				}(key)
+	for addr, conn := range r.targetToConn {
+		if _, ok := live[addr]; ok {
+			continue
+		}
+
+		r.shouldUpdate.Store(true)
+		delete(r.targetToConn, addr)
+		if err := conn.Close(); err != nil {
+			r.logger.Debug("failed to close connection",
+				r.redact.SystemField("address", addr),
+				zap.Error(err))
+		}
+	}
+}
+
+// removeStaleBucketsLocked stops the watchers for any buckets which have been deleted.
+func (r *customResolver) removeStaleBucketsLocked(buckets []string) {
+	live := make(map[string]struct{}, len(buckets))
+	for _, bucketName := range buckets {
+		live[bucketName] = struct{}{}
+	}
+
+	for key, w := range r.watchers {
+		if _, ok := live[w.bucketName]; !ok {
+			r.stopWatcherLocked(key, w)
+		}
+	}
+}
+
+func (r *customResolver) stopWatcherLocked(key string, w *routingWatcher) {
+	r.shouldUpdate.Store(true)
+	w.cancel()
+	delete(r.watchers, key)
+	delete(r.routingInfoMap, key)
+}
+
+func (r *customResolver) startWatcherLocked(key, bucketName, addr string, conn *grpc.ClientConn) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.watchers[key] = &routingWatcher{
+		bucketName: bucketName,
+		addr:       addr,
+		cancel:     cancel,
+	}
+
+	go r.watchRouting(ctx, key, bucketName, conn)
+}
+
+// watchRouting streams routing updates for a bucket, re-establishing the stream
+// with backoff whenever it fails, until the watcher is stopped.
+func (r *customResolver) watchRouting(ctx context.Context, key, bucketName string, conn *grpc.ClientConn) {
+	calcBackoff := exponentialBackoff(watchRoutingMinBackoff, watchRoutingMaxBackoff, 2)
+	rClient := routing_v2.NewRoutingServiceClient(conn)
+
+	var retryAttempts uint32
+	for {
+		rStream, err := rClient.WatchRouting(ctx, &routing_v2.WatchRoutingRequest{
+			BucketName: &bucketName,
+		})
+		for err == nil {
+			var resp *routing_v2.WatchRoutingResponse
+			resp, err = rStream.Recv()
+			if err != nil {
+				break
+			}
+
+			retryAttempts = 0
+			if !r.sendRoutingUpdate(ctx, routingUpdate{key: key, resp: resp}) {
+				return
 			}
+		}
 
-			bucketToLocalVBs[b.BucketName] = resp.VbucketDataRouting.LocalVbuckets
-			bucketToNumVBs[b.BucketName] = resp.VbucketDataRouting.NumVbuckets
+		if ctx.Err() != nil {
+			return
 		}
 
+		r.logger.Warn("watch routing stream failed",
+			r.redact.MetaField("bucket", bucketName),
+			r.redact.SystemField("address", conn.Target()),
+			zap.Error(err))
+
+		if !r.sendRoutingUpdate(ctx, routingUpdate{key: key, err: err}) {
+			return
+		}
+
+		select {
+		case <-time.After(calcBackoff(retryAttempts)):
+			retryAttempts++
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *customResolver) sendRoutingUpdate(ctx context.Context, u routingUpdate) bool {
+	select {
+	case r.routingUpdates <- u:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-r.done:
+		return false
+	}
+}
+
+// updateState pushes our current endpoints and their routing to the ClientConn, if
+// anything has changed since we last did so.
+func (r *customResolver) updateState() {
+	if !r.shouldUpdate.Swap(false) {
+		return
+	}
+
+	r.routingLock.Lock()
+	eps := make([]resolver.Endpoint, len(r.addrs))
+	for i, addr := range r.addrs {
+		bucketToLocalVBs := make(map[string][]uint32)
+		bucketToNumVBs := make(map[string]uint32)
+
+		for _, bucketName := range r.buckets {
+			resp, ok := r.routingInfoMap[constructKey(bucketName, addr)]
+			if !ok || resp.VbucketDataRouting == nil {
+				continue
+			}
+
+			bucketToLocalVBs[bucketName] = resp.VbucketDataRouting.LocalVbuckets
+			bucketToNumVBs[bucketName] = resp.VbucketDataRouting.NumVbuckets
+		}
+
+		eps[i] = resolver.Endpoint{
+			Addresses: []resolver.Address{{Addr: addr}},
+		}
 		eps[i].Attributes = eps[i].Attributes.WithValue("localvbs", bucketToLocalVBs)
 		eps[i].Attributes = eps[i].Attributes.WithValue("numvbs", bucketToNumVBs)
 	}
+	r.routingLock.Unlock()
 
-	if r.shouldUpdate.Load() {
-		r.shouldUpdate.Store(false)
-		r.cc.UpdateState(resolver.State{
-			Endpoints:     eps,
-			ServiceConfig: r.cc.ParseServiceConfig(fmt.Sprintf(`{"loadBalancingPolicy": "%s"}`, customLBName)),
-		})
+	if len(eps) == 0 {
+		return
 	}
 
-	return nil
+	err := r.cc.UpdateState(resolver.State{
+		Endpoints:     eps,
+		ServiceConfig: r.cc.ParseServiceConfig(fmt.Sprintf(`{"loadBalancingPolicy": "%s"}`, customLBName)),
+	})
+	if err != nil {
+		r.logger.Debug("failed to update resolver state", zap.Error(err))
+	}
 }
 
 func (r *customResolver) watch() {
@@ -226,15 +489,18 @@ func (r *customResolver) watch() {
 		case <-ticker.C:
 			ticker.Stop()
 			if err := r.resolve(); err != nil {
-				r.logger.Error("optimized routing resolution failed", zap.Error(err))
+				r.logger.Error("optimized routing resolution failed",
+					r.redact.SystemField("target", r.target.Endpoint()),
+					zap.Error(err))
 			}
 		case <-r.resolveNow:
 			ticker.Stop()
 			if err := r.resolve(); err != nil {
-				r.logger.Error("optimized routing resolution failed", zap.Error(err))
+				r.logger.Error("optimized routing resolution failed",
+					r.redact.SystemField("target", r.target.Endpoint()),
+					zap.Error(err))
 			}
 		case <-r.done:
-			close(r.routingUpdates)
 			return
 		}
 
@@ -242,24 +508,77 @@ func (r *customResolver) watch() {
 	}
 }
 
+// ResolveNow requests a resolution without blocking, requests made whilst one is
+// already pending are coalesced.
 func (r *customResolver) ResolveNow(resolver.ResolveNowOptions) {
-	r.resolveNow <- struct{}{}
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+// refresh performs a resolution immediately, waiting for it to complete.
+func (r *customResolver) refresh(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- r.resolve()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (r *customResolver) Close() {
 	close(r.done)
+	r.cancel()
+	if r.onClose != nil {
+		r.onClose(r)
+	}
+
+	r.routingLock.Lock()
+	defer r.routingLock.Unlock()
+
+	for key, w := range r.watchers {
+		w.cancel()
+		delete(r.watchers, key)
+	}
+
+	for addr, conn := range r.targetToConn {
+		_ = conn.Close()
+		delete(r.targetToConn, addr)
+	}
 }
 
-func resolveAddrs(target string) ([]string, error) {
+// resolveAddrs resolves the addresses for a target endpoint. If the target has no
+// port then the _couchbase2._tcp SRV records for the host are tried first, falling
+// back to the default port if none exist.
+func resolveAddrs(ctx context.Context, dns DNSResolver, target string, redact redactor) ([]string, error) {
 	var addrs []string
 	host, port, err := net.SplitHostPort(target)
 	if err != nil {
-		return addrs, fmt.Errorf("parsing target endpoint: %w", err)
+		srvAddrs, srvErr := resolveSRVAddrs(ctx, dns, target)
+		if srvErr == nil && len(srvAddrs) > 0 {
+			return srvAddrs, nil
+		}
+
+		seed, err := seedWithDefaultPort(target)
+		if err != nil {
+			return addrs, fmt.Errorf("parsing target endpoint: %w", err)
+		}
+
+		host, port, err = net.SplitHostPort(seed)
+		if err != nil {
+			return addrs, fmt.Errorf("parsing target endpoint: %w", err)
+		}
 	}
 
-	ips, err := net.LookupIP(host)
+	ips, err := dns.LookupIP(ctx, "ip", host)
 	if err != nil {
-		return addrs, fmt.Errorf("resolving addresses from hostname: %w", err)
+		return addrs, fmt.Errorf("resolving addresses from hostname '%s': %w", redact.SystemData(host), err)
 	}
 
 	for _, ip := range ips {
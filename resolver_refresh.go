@@ -0,0 +1,69 @@
+package gocbcoreps
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// refreshableBuilder wraps a resolver builder which we do not own, such as the
+// grpc dns resolver, tracking the resolvers it builds so that they can be asked
+// to re-resolve. Unlike our own resolvers, re-resolution is only requested and is
+// not waited for.
+type refreshableBuilder struct {
+	resolver.Builder
+
+	lock      sync.Mutex
+	resolvers map[*refreshableResolver]struct{}
+}
+
+func newRefreshableBuilder(builder resolver.Builder) *refreshableBuilder {
+	return &refreshableBuilder{
+		Builder:   builder,
+		resolvers: make(map[*refreshableResolver]struct{}),
+	}
+}
+
+func (b *refreshableBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	inner, err := b.Builder.Build(target, cc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &refreshableResolver{Resolver: inner, builder: b}
+
+	b.lock.Lock()
+	b.resolvers[r] = struct{}{}
+	b.lock.Unlock()
+
+	return r, nil
+}
+
+func (b *refreshableBuilder) refreshRouting(ctx context.Context) (int, error) {
+	b.lock.Lock()
+	resolvers := make([]*refreshableResolver, 0, len(b.resolvers))
+	for r := range b.resolvers {
+		resolvers = append(resolvers, r)
+	}
+	b.lock.Unlock()
+
+	for _, r := range resolvers {
+		r.ResolveNow(resolver.ResolveNowOptions{})
+	}
+
+	return len(resolvers), nil
+}
+
+type refreshableResolver struct {
+	resolver.Resolver
+	builder *refreshableBuilder
+}
+
+func (r *refreshableResolver) Close() {
+	r.builder.lock.Lock()
+	delete(r.builder.resolvers, r)
+	r.builder.lock.Unlock()
+
+	r.Resolver.Close()
+}
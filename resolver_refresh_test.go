@@ -0,0 +1,147 @@
+package gocbcoreps
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+type countingResolver struct {
+	resolveNows int
+	closed      bool
+}
+
+func (r *countingResolver) ResolveNow(resolver.ResolveNowOptions) { r.resolveNows++ }
+func (r *countingResolver) Close()                                { r.closed = true }
+
+type countingResolverBuilder struct {
+	built []*countingResolver
+}
+
+func (b *countingResolverBuilder) Build(resolver.Target, resolver.ClientConn, resolver.BuildOptions) (resolver.Resolver, error) {
+	r := &countingResolver{}
+	b.built = append(b.built, r)
+	return r, nil
+}
+
+func (b *countingResolverBuilder) Scheme() string { return "counting" }
+
+func TestRefreshableBuilderRefreshesActiveResolvers(t *testing.T) {
+	inner := &countingResolverBuilder{}
+	builder := newRefreshableBuilder(inner)
+
+	r, err := builder.Build(resolver.Target{}, nil, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n, err := builder.refreshRouting(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("expected one resolver to be refreshed, got %d: %v", n, err)
+	}
+	if inner.built[0].resolveNows != 1 {
+		t.Fatalf("expected ResolveNow to be called once, got %d", inner.built[0].resolveNows)
+	}
+
+	r.Close()
+	if !inner.built[0].closed {
+		t.Fatal("expected the inner resolver to be closed")
+	}
+
+	n, _ = builder.refreshRouting(context.Background())
+	if n != 0 {
+		t.Fatalf("expected no resolvers after close, got %d", n)
+	}
+}
+
+func TestRefreshRoutingNotSupported(t *testing.T) {
+	client := &RoutingClient{
+		refreshers: []routingRefresher{newRefreshableBuilder(&countingResolverBuilder{})},
+	}
+
+	err := client.RefreshRouting(context.Background())
+	if !errors.Is(err, ErrRefreshNotSupported) {
+		t.Fatalf("expected ErrRefreshNotSupported, got %v", err)
+	}
+}
+
+func TestRefreshRoutingRefreshesSeedResolver(t *testing.T) {
+	inner := &countingResolverBuilder{}
+	builder := newRefreshableBuilder(inner)
+	if _, err := builder.Build(resolver.Target{}, nil, resolver.BuildOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client := &RoutingClient{
+		refreshers: []routingRefresher{&SRVResolverBuilder{}, builder},
+	}
+
+	if err := client.RefreshRouting(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inner.built[0].resolveNows != 1 {
+		t.Fatalf("expected ResolveNow to be called once, got %d", inner.built[0].resolveNows)
+	}
+}
+
+// lookupCountingDNSResolver resolves every SRV lookup to a single record, counting
+// the lookups made.
+type lookupCountingDNSResolver struct {
+	lookups atomic.Int32
+}
+
+func (r *lookupCountingDNSResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.lookups.Add(1)
+	return "", []*net.SRV{{Target: "node1.cb.test.", Port: 18098}}, nil
+}
+
+func (r *lookupCountingDNSResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	return []net.IP{net.IPv4(127, 0, 0, 1)}, nil
+}
+
+func TestRefreshRoutingReResolvesSRVTarget(t *testing.T) {
+	dns := &lookupCountingDNSResolver{}
+	client, err := DialSeedsContext(context.Background(), []string{RoutingScheme + "://cb.test"}, &DialOptions{
+		InsecureTransport: true,
+		DNSResolver:       dns,
+	})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for dns.lookups.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("target was never resolved")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	before := dns.lookups.Load()
+
+	if err := client.RefreshRouting(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if after := dns.lookups.Load(); after <= before {
+		t.Fatalf("expected refresh to look up SRV records again, got %d lookups before and %d after", before, after)
+	}
+}
+
+func TestRefreshRoutingSeedListNotSupported(t *testing.T) {
+	client, err := DialSeedsContext(context.Background(), []string{"h1.cb.test", "h2.cb.test"}, &DialOptions{
+		InsecureTransport: true,
+	})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.RefreshRouting(context.Background()); !errors.Is(err, ErrRefreshNotSupported) {
+		t.Fatalf("expected ErrRefreshNotSupported, got %v", err)
+	}
+}
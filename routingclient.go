@@ -3,11 +3,17 @@ package gocbcoreps
 import (
 	"context"
 	"crypto/x509"
-	"net"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
 	"sync"
+	"time"
 
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/resolver"
 
 	grpc_logsettable "github.com/grpc-ecosystem/go-grpc-middleware/logging/settable"
@@ -30,10 +36,15 @@ import (
 )
 
 type RoutingClient struct {
-	routing *atomicRoutingTable
-	lock    sync.Mutex
-	logger  *zap.Logger
-	auth    Authenticator
+	routing        *atomicRoutingTable
+	lock           sync.Mutex
+	logger         *zap.Logger
+	redact         redactor
+	auth           Authenticator
+	refreshers     []routingRefresher
+	maxSendMsgSize int
+	clientID       string
+	prepared       *preparedStatementCache
 }
 
 // Verify that RoutingClient implements Conn
@@ -43,33 +54,160 @@ type DialOptions struct {
 	RootCAs            *x509.CertPool
 	Authenticator      Authenticator
 	Logger             *zap.Logger
+	LogHandler         slog.Handler
 	InsecureSkipVerify bool
 	PoolSize           uint32
 	TracerProvider     trace.TracerProvider
 	MeterProvider      metric.MeterProvider
+
+	// InsecureTransport disables TLS entirely and uses plaintext HTTP/2 (h2c),
+	// this is only intended for local development against an emulator.
+	InsecureTransport bool
+
+	// AllowInsecureCredentials permits basic credentials to be sent when
+	// InsecureTransport is enabled. By default, this is refused.
+	AllowInsecureCredentials bool
+
+	// RedactionLevel controls which data is redacted from logs and errors.
+	RedactionLevel RedactionLevel
+
+	// HashRedactedUserData hashes user data rather than only tagging it.
+	HashRedactedUserData bool
+
+	// ResolveInterval is how often addresses and routing are re-resolved, by default
+	// this is 30 seconds.
+	ResolveInterval time.Duration
+
+	// Keepalive configures HTTP/2 keepalive pings, which prevent idle connections
+	// from being silently dropped by NAT gateways and load balancers.
+	Keepalive *KeepaliveOptions
+
+	// IdleTimeout is how long a connection may have no active RPCs before it is
+	// closed, it is transparently reconnected on the next RPC. Zero uses the grpc
+	// default.
+	IdleTimeout time.Duration
+
+	// WarmUpConnections makes dial establish every connection in the pool and
+	// wait for each to become ready, rather than connecting lazily on first use.
+	// This waits for the transport (including TLS) to be established, it does
+	// not send any RPCs. If any connection fails to become ready within
+	// WarmUpTimeout then dial fails.
+	WarmUpConnections bool
+
+	// WarmUpTimeout bounds how long WarmUpConnections waits for the pool to
+	// become ready, by default this is 10 seconds.
+	WarmUpTimeout time.Duration
+
+	// MaxRecvMsgSize is the largest message which can be received, by default
+	// this is 25MiB.
+	MaxRecvMsgSize int
+
+	// MaxSendMsgSize is the largest message which can be sent, by default this is
+	// unlimited. KV requests exceeding it fail with a ValueTooLargeError before
+	// being sent. A grpc.MaxCallSendMsgSize in DefaultCallOptions takes precedence.
+	MaxSendMsgSize int
+
+	// DefaultCallOptions are applied to every RPC made by this client.
+	DefaultCallOptions []grpc.CallOption
+
+	// Compression enables compression of requests, by default requests are not
+	// compressed.
+	Compression *CompressionOptions
+
+	// ContextDialer is used to establish every connection to the cluster, for
+	// example to connect through an SSH tunnel.
+	ContextDialer ContextDialer
+
+	// ProxyURL connects through the given proxy, supported schemes are http (HTTP
+	// CONNECT) and socks5. Credentials can be included in the URL. If ContextDialer
+	// is also specified then it is used to connect to the proxy.
+	ProxyURL string
+
+	// Network selects whether addresses learned by the resolver are rewritten by
+	// AddressRewriter before being connected to, by default NetworkAuto is used.
+	// NetworkExternal requires an AddressRewriter, otherwise dial fails with
+	// ErrInvalidNetwork.
+	Network NetworkType
+
+	// AddressRewriter maps addresses learned by the resolver to those which are
+	// reachable by this client, for example when running outside of Kubernetes.
+	AddressRewriter AddressRewriter
+
+	// ClientName identifies the application using this client in server side logs,
+	// it is sent as metadata and included in the user agent.
+	ClientName string
+
+	// UserAgent is prepended to the user agent sent to the server, followed by
+	// ClientName if set. The library name and version are always included.
+	UserAgent string
+
+	// DNSResolver is used to resolve SRV records and hostnames for couchbase2://
+	// and couchbase2+optimized:// targets, by default net.DefaultResolver is used.
+	// Plain host:port seeds are not resolved with it: a single seed uses grpc's
+	// dns resolver, and multiple seeds, or seeds dialled through a proxy or
+	// ContextDialer, are resolved when they are dialled.
+	DNSResolver DNSResolver
+
+	// ReplaceGrpcLogger replaces the process-wide grpc logger with one which writes
+	// to our logger. This affects every grpc client in the process.
+	ReplaceGrpcLogger bool
+
+	// PreparedStatementCacheSize is the number of prepared query statements which
+	// are tracked before the least recently used is evicted, by default 5000.
+	PreparedStatementCacheSize int
+}
+
+// KeepaliveOptions configures the keepalive pings sent on each connection.
+type KeepaliveOptions struct {
+	// Time is how long a connection must be inactive before a ping is sent. grpc
+	// enforces a minimum of 10 seconds.
+	Time time.Duration
+
+	// Timeout is how long to wait for a ping acknowledgement before closing the
+	// connection.
+	Timeout time.Duration
+
+	// PermitWithoutStream allows pings to be sent when there are no active RPCs.
+	PermitWithoutStream bool
 }
 
+// Dial connects to the given target, which may be a comma separated list of seed
+// addresses.
 func Dial(target string, opts *DialOptions) (*RoutingClient, error) {
 	return DialContext(context.Background(), target, opts)
 }
 
 func DialContext(ctx context.Context, target string, opts *DialOptions) (*RoutingClient, error) {
-	// use port 18091 by default
-	{
-		_, _, err := net.SplitHostPort(target)
-		if err != nil {
-			// if we couldn't split the host/port, assume there is no port
-			target = target + ":18098"
-		}
+	if strings.Contains(target, "://") {
+		return DialSeedsContext(ctx, []string{target}, opts)
 	}
 
-	logger := opts.Logger
-	if logger == nil {
-		logger = zap.NewNop()
+	return DialSeedsContext(ctx, parseSeeds(target), opts)
+}
+
+// DialSeeds connects using the given seed addresses, failing over between them
+// when any are unreachable.
+func DialSeeds(seeds []string, opts *DialOptions) (*RoutingClient, error) {
+	return DialSeedsContext(context.Background(), seeds, opts)
+}
+
+func DialSeedsContext(ctx context.Context, seeds []string, opts *DialOptions) (*RoutingClient, error) {
+	seeds, err := normalizeSeeds(seeds)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := newLogger(opts.Logger, opts.LogHandler)
+
+	redact := redactor{
+		level:        opts.RedactionLevel,
+		hashUserData: opts.HashRedactedUserData,
 	}
 
-	// Setup grpc level logging, so that we can pipe connection level issues into our logs.
-	grpc_logsettable.ReplaceGrpcLoggerV2().Set(zapgrpc.NewLogger(logger))
+	if opts.ReplaceGrpcLogger {
+		// Setup grpc level logging, so that we can pipe connection level issues into our logs.
+		grpc_logsettable.ReplaceGrpcLoggerV2().Set(zapgrpc.NewLogger(logger))
+	}
 
 	var conns []*routingConn
 
@@ -78,37 +216,138 @@ func DialContext(ctx context.Context, target string, opts *DialOptions) (*Routin
 		poolSize = opts.PoolSize
 	}
 
-	resolver.Register(&CustomResolverBuilder{
+	resolveInterval := defaultResolveInterval
+	if opts.ResolveInterval > 0 {
+		resolveInterval = opts.ResolveInterval
+	}
+
+	contextDialer := opts.ContextDialer
+	if opts.ProxyURL != "" {
+		proxyDialer, err := newProxyDialer(opts.ProxyURL, contextDialer)
+		if err != nil {
+			return nil, err
+		}
+
+		contextDialer = proxyDialer
+	}
+
+	addrMapper := addressMapper{
+		network: opts.Network,
+		rewrite: opts.AddressRewriter,
+		logger:  logger,
+		redact:  redact,
+	}
+	if err := addrMapper.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Compression != nil {
+		if err := opts.Compression.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	identity := newClientIdentity(opts.ClientName, opts.UserAgent)
+
+	optimizedBuilder := &CustomResolverBuilder{
+		ctx:                      ctx,
+		logger:                   logger,
+		auth:                     opts.Authenticator,
+		allowInsecureCredentials: opts.AllowInsecureCredentials,
+		redact:                   redact,
+		dns:                      opts.DNSResolver,
+		contextDialer:            contextDialer,
+		addrMapper:               addrMapper,
+		identity:                 identity,
+		resolveInterval:          resolveInterval,
+	}
+	resolver.Register(optimizedBuilder)
+
+	srvBuilder := &SRVResolverBuilder{
 		ctx:             ctx,
 		logger:          logger,
-		auth:            opts.Authenticator,
-		resolveInterval: defaultResolveInterval,
-	})
+		redact:          redact,
+		dns:             opts.DNSResolver,
+		addrMapper:      addrMapper,
+		resolveInterval: resolveInterval,
+	}
+	resolver.Register(srvBuilder)
+
+	var keepaliveParams *keepalive.ClientParameters
+	if opts.Keepalive != nil {
+		keepaliveParams = &keepalive.ClientParameters{
+			Time:                opts.Keepalive.Time,
+			Timeout:             opts.Keepalive.Timeout,
+			PermitWithoutStream: opts.Keepalive.PermitWithoutStream,
+		}
+	}
 
+	refreshers := []routingRefresher{optimizedBuilder, srvBuilder}
 	for i := uint32(0); i < poolSize; i++ {
+		target, seedDialOpts, seedRefresher := seedDialTarget(seeds, int(i), contextDialer != nil)
+		if seedRefresher != nil {
+			refreshers = append(refreshers, seedRefresher)
+		}
+
 		conn, err := dialRoutingConn(ctx, target, &routingConnOptions{
-			RootCAs:            opts.RootCAs,
-			Authenticator:      opts.Authenticator,
-			InsecureSkipVerify: opts.InsecureSkipVerify,
-			TracerProvider:     opts.TracerProvider,
-			MeterProvider:      opts.MeterProvider,
+			RootCAs:                  opts.RootCAs,
+			Authenticator:            opts.Authenticator,
+			InsecureSkipVerify:       opts.InsecureSkipVerify,
+			InsecureTransport:        opts.InsecureTransport,
+			AllowInsecureCredentials: opts.AllowInsecureCredentials,
+			TracerProvider:           opts.TracerProvider,
+			MeterProvider:            opts.MeterProvider,
+			Logger:                   logger,
+			Redact:                   redact,
+			// Our resolvers are also registered globally, but we make sure that our own
+			// connections always use the builders which belong to this client.
+			ExtraDialOptions:   append(seedDialOpts, grpc.WithResolvers(optimizedBuilder, srvBuilder)),
+			Keepalive:          keepaliveParams,
+			IdleTimeout:        opts.IdleTimeout,
+			MaxRecvMsgSize:     opts.MaxRecvMsgSize,
+			MaxSendMsgSize:     opts.MaxSendMsgSize,
+			DefaultCallOptions: opts.DefaultCallOptions,
+			Compression:        opts.Compression,
+			ContextDialer:      contextDialer,
+			Identity:           identity,
 		})
 		if err != nil {
+			_ = newRoutingConnPool(conns).Close()
 			return nil, err
 		}
 
 		conns = append(conns, conn)
 	}
 
+	pool := newRoutingConnPool(conns)
+	if opts.WarmUpConnections {
+		warmUpTimeout := defaultWarmUpTimeout
+		if opts.WarmUpTimeout > 0 {
+			warmUpTimeout = opts.WarmUpTimeout
+		}
+
+		warmUpCtx, cancel := context.WithTimeout(ctx, warmUpTimeout)
+		err := pool.WaitForReady(warmUpCtx)
+		cancel()
+		if err != nil {
+			_ = pool.Close()
+			return nil, fmt.Errorf("failed to warm up connections: %w", err)
+		}
+	}
+
 	routing := &atomicRoutingTable{}
 	routing.Store(&routingTable{
-		Conns: newRoutingConnPool(conns),
+		Conns: pool,
 	})
 
 	return &RoutingClient{
-		routing: routing,
-		logger:  logger,
-		auth:    opts.Authenticator,
+		routing:        routing,
+		logger:         logger,
+		redact:         redact,
+		auth:           opts.Authenticator,
+		refreshers:     refreshers,
+		maxSendMsgSize: sendMsgSizeLimit(opts.MaxSendMsgSize, opts.DefaultCallOptions),
+		clientID:       identity.id,
+		prepared:       newPreparedStatementCache(opts.PreparedStatementCacheSize),
 	}, nil
 }
 
@@ -125,8 +364,18 @@ func (c *RoutingClient) ReconfigureAuthenticator(opts ReconfigureAuthenticatorOp
 	case *BasicAuthenticator:
 		switch na := auth.(type) {
 		case *BasicAuthenticator:
-			data := na.encodedData.Load()
-			a.encodedData.Store(data)
+			a.encodedData.Store(na.encodedData.Load())
+			a.username.Store(na.username.Load())
+		default:
+			return ErrAuthenticatorMismatch
+		}
+	case *CredentialProviderAuthenticator:
+		switch na := auth.(type) {
+		case *CredentialProviderAuthenticator:
+			na.lock.Lock()
+			provider := na.provider
+			na.lock.Unlock()
+			a.UpdateProvider(provider)
 		default:
 			return ErrAuthenticatorMismatch
 		}
@@ -145,6 +394,55 @@ func (c *RoutingClient) ReconfigureAuthenticator(opts ReconfigureAuthenticatorOp
 	return nil
 }
 
+// ClientID returns the random identifier which is sent with every request made by
+// this client.
+func (c *RoutingClient) ClientID() string {
+	return c.clientID
+}
+
+// PreparedStatementStats returns the usage of the prepared statement cache.
+func (c *RoutingClient) PreparedStatementStats() PreparedStatementStats {
+	return c.prepared.stats()
+}
+
+// ClearPreparedStatements empties the prepared statement cache.
+func (c *RoutingClient) ClearPreparedStatements() {
+	c.prepared.clear()
+}
+
+// RefreshRouting forces the addresses and routing for this client to be resolved
+// immediately, for example after a rebalance. For the couchbase2 schemes this waits
+// for resolution to complete, for other targets, such as plain hostnames, the
+// re-resolution is requested but not waited for. ErrRefreshNotSupported is returned
+// if no resolver was able to be refreshed, such as for a list of seeds, which are
+// resolved each time they are dialled.
+func (c *RoutingClient) RefreshRouting(ctx context.Context) error {
+	var errs []error
+	refreshed := 0
+	for _, refresher := range c.refreshers {
+		n, err := refresher.refreshRouting(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		refreshed += n
+	}
+
+	if refreshed == 0 && len(errs) == 0 {
+		return ErrRefreshNotSupported
+	}
+
+	return errors.Join(errs...)
+}
+
+// WarmUp connects any idle connections in the pool and waits until they are all
+// ready, or ctx is done. This can be used to avoid paying connection setup costs
+// on the first requests after a quiet period.
+func (c *RoutingClient) WarmUp(ctx context.Context) error {
+	r := c.routing.Load()
+
+	return r.Conns.WaitForReady(ctx)
+}
+
 func (c *RoutingClient) ConnectionState() ConnState {
 	r := c.routing.Load()
 
@@ -173,7 +471,7 @@ func (c *RoutingClient) RoutingV2() routing_v2.RoutingServiceClient {
 }
 
 func (c *RoutingClient) KvV1() kv_v1.KvServiceClient {
-	return &routingImpl_KvV1{c}
+	return &routingImpl_KvV1{client: c}
 }
 
 func (c *RoutingClient) QueryV1() query_v1.QueryServiceClient {
@@ -0,0 +1,54 @@
+package gocbcoreps
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestDialWarmUpConnectionsWaitsForReady(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	srv := grpc.NewServer()
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	client, err := DialSeedsContext(context.Background(), []string{lis.Addr().String()}, &DialOptions{
+		InsecureTransport: true,
+		PoolSize:          2,
+		WarmUpConnections: true,
+		WarmUpTimeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+
+	if state := client.ConnectionState(); state != ConnStateOnline {
+		t.Fatalf("expected pool to be online after warm up, got %v", state)
+	}
+}
+
+func TestDialWarmUpConnectionsFailure(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+
+	_, err = DialSeedsContext(context.Background(), []string{addr}, &DialOptions{
+		InsecureTransport: true,
+		WarmUpConnections: true,
+		WarmUpTimeout:     200 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatalf("expected dial to fail when connections cannot become ready")
+	}
+}
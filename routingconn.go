@@ -4,10 +4,13 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
+	"time"
 
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 
 	"github.com/couchbase/goprotostellar/genproto/routing_v2"
 	"github.com/couchbase/goprotostellar/genproto/view_v1"
@@ -26,16 +29,31 @@ import (
 	"github.com/couchbase/goprotostellar/genproto/search_v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 )
 
 type routingConnOptions struct {
-	InsecureSkipVerify bool // used for enabling TLS, but skipping verification
-	RootCAs            *x509.CertPool
-	Authenticator      Authenticator
-	TracerProvider     trace.TracerProvider
-	MeterProvider      metric.MeterProvider
+	InsecureSkipVerify       bool // used for enabling TLS, but skipping verification
+	InsecureTransport        bool // used for disabling TLS entirely (h2c)
+	AllowInsecureCredentials bool
+	RootCAs                  *x509.CertPool
+	Authenticator            Authenticator
+	TracerProvider           trace.TracerProvider
+	MeterProvider            metric.MeterProvider
+	Logger                   *zap.Logger
+	Redact                   redactor
+	ExtraDialOptions         []grpc.DialOption
+	Keepalive                *keepalive.ClientParameters
+	IdleTimeout              time.Duration
+	MaxRecvMsgSize           int
+	MaxSendMsgSize           int
+	DefaultCallOptions       []grpc.CallOption
+	Compression              *CompressionOptions
+	ContextDialer            ContextDialer
+	Identity                 clientIdentity
 }
 
 type routingConn struct {
@@ -55,39 +73,16 @@ type routingConn struct {
 // Verify that routingConn implements Conn
 var _ Conn = (*routingConn)(nil)
 
-const maxMsgSize = 26214400 // 25MiB
+const defaultMaxRecvMsgSize = 26214400 // 25MiB
 
-func dialRoutingConn(ctx context.Context, address string, opts *routingConnOptions) (*routingConn, error) {
-	var perRpcDialOpt grpc.DialOption
-	var getClientCertificate func(info *tls.CertificateRequestInfo) (*tls.Certificate, error)
+const defaultWarmUpTimeout = time.Second * 10
 
-	switch a := opts.Authenticator.(type) {
-	case *BasicAuthenticator:
-		perRpcDialOpt = grpc.WithPerRPCCredentials(a)
-	case *CertificateAuthenticator:
-		getClientCertificate = a.GetClientCertificate
-	}
-
-	pool, err := x509.SystemCertPool()
+func dialRoutingConn(ctx context.Context, address string, opts *routingConnOptions) (*routingConn, error) {
+	dialOpts, err := transportDialOptions(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	if opts.RootCAs != nil {
-		pool = opts.RootCAs
-	}
-
-	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(
-		&tls.Config{
-			InsecureSkipVerify:   opts.InsecureSkipVerify,
-			RootCAs:              pool,
-			GetClientCertificate: getClientCertificate,
-		},
-	))}
-	if perRpcDialOpt != nil {
-		dialOpts = append(dialOpts, perRpcDialOpt)
-	}
-
 	clientOpts := []otelgrpc.Option{
 		otelgrpc.WithPropagators(propagation.TraceContext{}),
 	}
@@ -97,8 +92,37 @@ func dialRoutingConn(ctx context.Context, address string, opts *routingConnOptio
 	if opts.MeterProvider != nil {
 		clientOpts = append(clientOpts, otelgrpc.WithMeterProvider(opts.MeterProvider))
 	}
-	dialOpts = append(dialOpts, grpc.WithStatsHandler(otelgrpc.NewClientHandler(clientOpts...)))
-	dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxRecvMsgSizeCallOption{MaxRecvMsgSize: maxMsgSize}))
+	dialOpts = append(dialOpts, grpc.WithStatsHandler(impersonationStatsHandler{
+		Handler: otelgrpc.NewClientHandler(clientOpts...),
+		auth:    opts.Authenticator,
+		redact:  opts.Redact,
+	}))
+	dialOpts = append(dialOpts, impersonationDialOptions(opts.Logger, opts.Redact, opts.Authenticator)...)
+
+	maxRecvMsgSize := defaultMaxRecvMsgSize
+	if opts.MaxRecvMsgSize > 0 {
+		maxRecvMsgSize = opts.MaxRecvMsgSize
+	}
+	callOpts := []grpc.CallOption{grpc.MaxCallRecvMsgSize(maxRecvMsgSize)}
+	if opts.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(opts.MaxSendMsgSize))
+	}
+	callOpts = append(callOpts, opts.DefaultCallOptions...)
+	dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(callOpts...))
+	dialOpts = append(dialOpts, opts.Identity.dialOptions()...)
+	if opts.ContextDialer != nil {
+		dialOpts = append(dialOpts, grpc.WithContextDialer(opts.ContextDialer))
+	}
+	if opts.Compression != nil {
+		dialOpts = append(dialOpts, compressionDialOptions(opts.Compression, opts.MeterProvider)...)
+	}
+	if opts.Keepalive != nil {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(*opts.Keepalive))
+	}
+	if opts.IdleTimeout > 0 {
+		dialOpts = append(dialOpts, grpc.WithIdleTimeout(opts.IdleTimeout))
+	}
+	dialOpts = append(dialOpts, opts.ExtraDialOptions...)
 
 	conn, err := grpc.DialContext(ctx, address, dialOpts...)
 	if err != nil {
@@ -120,6 +144,75 @@ func dialRoutingConn(ctx context.Context, address string, opts *routingConnOptio
 	}, nil
 }
 
+// sendMsgSizeLimit returns the send message size limit which grpc applies when the
+// call options are layered over limit, later options take precedence.
+func sendMsgSizeLimit(limit int, opts []grpc.CallOption) int {
+	for _, opt := range opts {
+		if o, ok := opt.(grpc.MaxSendMsgSizeCallOption); ok {
+			limit = o.MaxSendMsgSize
+		}
+	}
+
+	return limit
+}
+
+func transportDialOptions(opts *routingConnOptions) ([]grpc.DialOption, error) {
+	var perRpcCreds credentials.PerRPCCredentials
+	var authDialOpts []grpc.DialOption
+	var getClientCertificate func(info *tls.CertificateRequestInfo) (*tls.Certificate, error)
+
+	switch a := opts.Authenticator.(type) {
+	case *BasicAuthenticator:
+		perRpcCreds = a
+	case *CredentialProviderAuthenticator:
+		perRpcCreds = a
+		authDialOpts = credentialRefreshDialOptions(a)
+	case *CertificateAuthenticator:
+		getClientCertificate = a.GetClientCertificate
+	}
+
+	if opts.InsecureTransport {
+		if getClientCertificate != nil {
+			// Certificate authentication is performed as part of the TLS handshake
+			// so cannot work over a plaintext connection.
+			return nil, ErrAuthenticatorUnsupported
+		}
+
+		dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+		if perRpcCreds != nil {
+			if !opts.AllowInsecureCredentials {
+				return nil, ErrInsecureCredentials
+			}
+
+			dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(insecurePerRPCCredentials{perRpcCreds}))
+		}
+
+		return append(dialOpts, authDialOpts...), nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.RootCAs != nil {
+		pool = opts.RootCAs
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(
+		&tls.Config{
+			InsecureSkipVerify:   opts.InsecureSkipVerify,
+			RootCAs:              pool,
+			GetClientCertificate: getClientCertificate,
+		},
+	))}
+	if perRpcCreds != nil {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(perRpcCreds))
+	}
+
+	return append(dialOpts, authDialOpts...), nil
+}
+
 func (c *routingConn) RoutingV2() routing_v2.RoutingServiceClient {
 	return c.routingV2
 }
@@ -160,6 +253,25 @@ func (c *routingConn) SearchAdminV1() admin_search_v1.SearchAdminServiceClient {
 	return c.searchAdminV1
 }
 
+// WaitForReady warms up the connection and waits until it is ready or ctx is done.
+func (c *routingConn) WaitForReady(ctx context.Context) error {
+	c.conn.Connect()
+
+	for {
+		state := c.conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if state == connectivity.Idle {
+			c.conn.Connect()
+		}
+
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection not ready (state %s): %w", c.conn.GetState(), ctx.Err())
+		}
+	}
+}
+
 func (c *routingConn) Close() error {
 	return c.conn.Close()
 }
@@ -173,7 +285,8 @@ func (c *routingConn) State() ConnState {
 	case connectivity.TransientFailure:
 		return ConnStateOffline
 	case connectivity.Idle:
-		return ConnStateOffline
+		// Idle connections have no transport, but will reconnect on demand.
+		return ConnStateIdle
 	case connectivity.Ready:
 		return ConnStateOnline
 	}
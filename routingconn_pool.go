@@ -1,6 +1,7 @@
 package gocbcoreps
 
 import (
+	"context"
 	"sync/atomic"
 )
 
@@ -39,22 +40,45 @@ func (pool *routingConnPool) Close() error {
 	return err
 }
 
+// WaitForReady starts connecting every idle connection and then waits until they
+// are all ready, or ctx is done.
+func (pool *routingConnPool) WaitForReady(ctx context.Context) error {
+	for _, conn := range pool.conns {
+		conn.conn.Connect()
+	}
+
+	for _, conn := range pool.conns {
+		if err := conn.WaitForReady(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (pool *routingConnPool) State() ConnState {
 	var numOnline uint32
 	var numOffline uint32
+	var numIdle uint32
 	for _, conn := range pool.conns {
 		switch conn.State() {
 		case ConnStateOffline:
 			numOffline++
 		case ConnStateOnline:
 			numOnline++
+		case ConnStateIdle:
+			numIdle++
 		}
 	}
 	if numOffline == pool.Size() {
 		// If all connections are offline then our state is offline.
 		return ConnStateOffline
-	} else if numOnline == pool.Size() {
-		// If all connections are online then our state is online.
+	} else if numIdle == pool.Size() {
+		// If all connections are idle then our state is idle.
+		return ConnStateIdle
+	} else if numOnline+numIdle == pool.Size() {
+		// If all connections are online, or idle and able to reconnect on demand, then
+		// our state is online.
 		return ConnStateOnline
 	} else {
 		// If we have some connections online and some offline then we're degraded.
@@ -0,0 +1,135 @@
+package gocbcoreps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// rowStream iterates the rows of a streaming response, where each message carries
+// a batch of rows. recv returns the rows of the next message, or io.EOF once the
+// stream has completed.
+type rowStream[T any] struct {
+	recv   func() ([]T, error)
+	cancel context.CancelFunc
+
+	rows     []T
+	row      T
+	hasRow   bool
+	err      error
+	finished bool
+	closed   bool
+}
+
+func (s *rowStream[T]) next() bool {
+	for len(s.rows) == 0 {
+		if s.finished || s.closed {
+			s.clearRow()
+			return false
+		}
+
+		rows, err := s.recv()
+		if err != nil {
+			s.finished = true
+			if !errors.Is(err, io.EOF) {
+				s.err = err
+			}
+			s.release()
+			continue
+		}
+
+		s.rows = rows
+	}
+
+	s.row = s.rows[0]
+	s.hasRow = true
+	s.rows = s.rows[1:]
+	return true
+}
+
+func (s *rowStream[T]) clearRow() {
+	var zero T
+	s.row = zero
+	s.hasRow = false
+}
+
+// one passes the first row to decode and closes the stream.
+func (s *rowStream[T]) one(decode func(T) error) error {
+	if !s.next() {
+		if err := s.close(); err != nil {
+			return err
+		}
+		return ErrNoResult
+	}
+
+	err := decode(s.row)
+	closeErr := s.close()
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
+
+func (s *rowStream[T]) release() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// close stops iteration. Without a cancel function the remaining rows are drained
+// so that the underlying stream is not leaked.
+func (s *rowStream[T]) close() error {
+	if s.closed {
+		return s.err
+	}
+
+	if s.cancel != nil {
+		s.release()
+	} else {
+		for !s.finished {
+			s.rows = nil
+			s.next()
+		}
+	}
+
+	s.closed = true
+	s.rows = nil
+	s.clearRow()
+	return s.err
+}
+
+// jsonRowStream is a rowStream whose rows are JSON encoded.
+type jsonRowStream struct {
+	rowStream[[]byte]
+}
+
+func (s *jsonRowStream) decodeRow(valuePtr interface{}) error {
+	if !s.hasRow {
+		return ErrNoResult
+	}
+
+	return decodeJSONRow(s.row, valuePtr)
+}
+
+func (s *jsonRowStream) oneRow(valuePtr interface{}) error {
+	return s.one(func(row []byte) error {
+		return decodeJSONRow(row, valuePtr)
+	})
+}
+
+func decodeJSONRow(row []byte, valuePtr interface{}) error {
+	if raw, ok := valuePtr.(*json.RawMessage); ok {
+		*raw = append((*raw)[:0], row...)
+		return nil
+	}
+
+	if err := json.Unmarshal(row, valuePtr); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+
+	return nil
+}
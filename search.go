@@ -0,0 +1,538 @@
+package gocbcoreps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/couchbase/goprotostellar/genproto/search_v1"
+)
+
+// SearchSort is a sort order for search hits.
+type SearchSort interface {
+	toProto() *search_v1.Sorting
+}
+
+type SearchSortScore struct {
+	Descending bool
+}
+
+func (s SearchSortScore) toProto() *search_v1.Sorting {
+	return &search_v1.Sorting{Sorting: &search_v1.Sorting_ScoreSorting{
+		ScoreSorting: &search_v1.ScoreSorting{Descending: s.Descending},
+	}}
+}
+
+type SearchSortID struct {
+	Descending bool
+}
+
+func (s SearchSortID) toProto() *search_v1.Sorting {
+	return &search_v1.Sorting{Sorting: &search_v1.Sorting_IdSorting{
+		IdSorting: &search_v1.IdSorting{Descending: s.Descending},
+	}}
+}
+
+type SearchSortField struct {
+	Field      string
+	Descending bool
+	Type       string
+	Mode       string
+	Missing    string
+}
+
+func (s SearchSortField) toProto() *search_v1.Sorting {
+	return &search_v1.Sorting{Sorting: &search_v1.Sorting_FieldSorting{
+		FieldSorting: &search_v1.FieldSorting{
+			Field:      s.Field,
+			Descending: s.Descending,
+			Type:       s.Type,
+			Mode:       s.Mode,
+			Missing:    s.Missing,
+		},
+	}}
+}
+
+type SearchSortGeoDistance struct {
+	Field      string
+	Location   GeoPoint
+	Unit       string
+	Descending bool
+}
+
+func (s SearchSortGeoDistance) toProto() *search_v1.Sorting {
+	return &search_v1.Sorting{Sorting: &search_v1.Sorting_GeoDistanceSorting{
+		GeoDistanceSorting: &search_v1.GeoDistanceSorting{
+			Field:      s.Field,
+			Center:     s.Location.toProto(),
+			Unit:       s.Unit,
+			Descending: s.Descending,
+		},
+	}}
+}
+
+// SearchFacet requests aggregate information about the matching documents.
+type SearchFacet interface {
+	toProto() *search_v1.Facet
+}
+
+type SearchTermFacet struct {
+	Field string
+	Size  uint32
+}
+
+func (f SearchTermFacet) toProto() *search_v1.Facet {
+	return &search_v1.Facet{Facet: &search_v1.Facet_TermFacet{
+		TermFacet: &search_v1.TermFacet{Field: f.Field, Size: f.Size},
+	}}
+}
+
+type SearchNumericRange struct {
+	Name string
+	Min  *float32
+	Max  *float32
+}
+
+type SearchNumericRangeFacet struct {
+	Field  string
+	Size   uint32
+	Ranges []SearchNumericRange
+}
+
+func (f SearchNumericRangeFacet) toProto() *search_v1.Facet {
+	ranges := make([]*search_v1.NumericRange, len(f.Ranges))
+	for i, r := range f.Ranges {
+		ranges[i] = &search_v1.NumericRange{Name: r.Name, Min: r.Min, Max: r.Max}
+	}
+
+	return &search_v1.Facet{Facet: &search_v1.Facet_NumericRangeFacet{
+		NumericRangeFacet: &search_v1.NumericRangeFacet{Field: f.Field, Size: f.Size, NumericRanges: ranges},
+	}}
+}
+
+type SearchDateRange struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+type SearchDateRangeFacet struct {
+	Field  string
+	Size   uint32
+	Ranges []SearchDateRange
+}
+
+func (f SearchDateRangeFacet) toProto() *search_v1.Facet {
+	ranges := make([]*search_v1.DateRange, len(f.Ranges))
+	for i, r := range f.Ranges {
+		dateRange := &search_v1.DateRange{Name: r.Name}
+		if !r.Start.IsZero() {
+			start := r.Start.Format(time.RFC3339)
+			dateRange.Start = &start
+		}
+		if !r.End.IsZero() {
+			end := r.End.Format(time.RFC3339)
+			dateRange.End = &end
+		}
+		ranges[i] = dateRange
+	}
+
+	return &search_v1.Facet{Facet: &search_v1.Facet_DateRangeFacet{
+		DateRangeFacet: &search_v1.DateRangeFacet{Field: f.Field, Size: f.Size, DateRanges: ranges},
+	}}
+}
+
+type SearchHighlight struct {
+	Style  search_v1.SearchQueryRequest_HighlightStyle
+	Fields []string
+}
+
+type SearchOptions struct {
+	Limit            uint32
+	Skip             uint32
+	Explain          bool
+	Highlight        *SearchHighlight
+	Fields           []string
+	Sort             []SearchSort
+	Facets           map[string]SearchFacet
+	DisableScoring   bool
+	IncludeLocations bool
+	Collections      []string
+
+	// VectorSearch adds vector queries which are run alongside the full-text query.
+	VectorSearch *VectorSearch
+}
+
+func (o *SearchOptions) toRequest(indexName string, query SearchQuery) (*search_v1.SearchQueryRequest, error) {
+	req := &search_v1.SearchQueryRequest{
+		IndexName:          indexName,
+		Limit:              o.Limit,
+		Skip:               o.Skip,
+		IncludeExplanation: o.Explain,
+		Fields:             o.Fields,
+		DisableScoring:     o.DisableScoring,
+		IncludeLocations:   o.IncludeLocations,
+		Collections:        o.Collections,
+	}
+
+	if query != nil {
+		req.Query = query.toProto()
+	} else if o.VectorSearch != nil {
+		req.Query = NewMatchNoneQuery().toProto()
+	}
+	if o.Highlight != nil {
+		req.HighlightStyle = o.Highlight.Style
+		req.HighlightFields = o.Highlight.Fields
+	}
+	for _, sort := range o.Sort {
+		req.Sort = append(req.Sort, sort.toProto())
+	}
+	if len(o.Facets) > 0 {
+		req.Facets = make(map[string]*search_v1.Facet, len(o.Facets))
+		for name, facet := range o.Facets {
+			req.Facets[name] = facet.toProto()
+		}
+	}
+	if o.VectorSearch != nil {
+		if err := o.VectorSearch.apply(req); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
+type SearchRowLocation struct {
+	Field          string
+	Term           string
+	Position       uint32
+	Start          uint32
+	End            uint32
+	ArrayPositions []uint32
+}
+
+type SearchRow struct {
+	Index       string
+	ID          string
+	Score       float64
+	Explanation json.RawMessage
+	Locations   []SearchRowLocation
+	Fragments   map[string][]string
+
+	fields map[string][]byte
+}
+
+// Fields decodes the stored fields of the hit, as a JSON object, into valuePtr.
+func (r *SearchRow) Fields(valuePtr interface{}) error {
+	fields := make(map[string]json.RawMessage, len(r.fields))
+	for name, value := range r.fields {
+		fields[name] = value
+	}
+
+	content, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+
+	if err := json.Unmarshal(content, valuePtr); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+
+	return nil
+}
+
+type SearchTermFacetResult struct {
+	Term  string
+	Count uint64
+}
+
+type SearchNumericRangeFacetResult struct {
+	Name  string
+	Min   uint64
+	Max   uint64
+	Count uint64
+}
+
+type SearchDateRangeFacetResult struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+	Count uint64
+}
+
+type SearchFacetResult struct {
+	Field         string
+	Total         int64
+	Missing       int64
+	Other         int64
+	Terms         []SearchTermFacetResult
+	NumericRanges []SearchNumericRangeFacetResult
+	DateRanges    []SearchDateRangeFacetResult
+}
+
+type SearchMetrics struct {
+	Took                  time.Duration
+	TotalRows             uint64
+	MaxScore              float64
+	TotalPartitionCount   uint64
+	SuccessPartitionCount uint64
+	ErrorPartitionCount   uint64
+}
+
+type SearchMetaData struct {
+	Metrics SearchMetrics
+	Errors  map[string]string
+}
+
+// SearchResult iterates over the hits of a search, the facets and metadata become
+// available once all hits have been read.
+type SearchResult struct {
+	rowStream[*SearchRow]
+
+	facets map[string]*search_v1.SearchQueryResponse_FacetResult
+	meta   *search_v1.SearchQueryResponse_MetaData
+}
+
+// NewSearchResult wraps a stream returned by SearchV1().SearchQuery. Closing the
+// result before all hits are read drains the stream, cancel the context used to
+// create the stream to abandon it instead.
+func NewSearchResult(stream search_v1.SearchService_SearchQueryClient) *SearchResult {
+	return newSearchResult(stream, nil)
+}
+
+func newSearchResult(stream search_v1.SearchService_SearchQueryClient, cancel context.CancelFunc) *SearchResult {
+	r := &SearchResult{
+		facets: make(map[string]*search_v1.SearchQueryResponse_FacetResult),
+	}
+	r.cancel = cancel
+	r.recv = func() ([]*SearchRow, error) {
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		for name, facet := range resp.Facets {
+			r.facets[name] = facet
+		}
+		if resp.MetaData != nil {
+			r.meta = resp.MetaData
+		}
+
+		hits := make([]*SearchRow, len(resp.Hits))
+		for i, hit := range resp.Hits {
+			hits[i] = searchRowFromProto(hit)
+		}
+
+		return hits, nil
+	}
+
+	return r
+}
+
+// Next advances to the next hit, returning false once there are no more hits or an
+// error occurred, in which case Err returns the error.
+func (r *SearchResult) Next() bool {
+	return r.next()
+}
+
+// Row returns the current hit.
+func (r *SearchResult) Row() *SearchRow {
+	return r.row
+}
+
+// Err returns any error which occurred during iteration.
+func (r *SearchResult) Err() error {
+	return r.err
+}
+
+// Close stops iteration and releases the underlying stream.
+func (r *SearchResult) Close() error {
+	return r.close()
+}
+
+// Rows returns an iterator over the hits of the result. The result is closed when
+// iteration stops, any stream error is yielded last.
+func (r *SearchResult) Rows() iter.Seq2[*SearchRow, error] {
+	return func(yield func(*SearchRow, error) bool) {
+		defer r.Close()
+
+		for r.Next() {
+			if !yield(r.Row(), nil) {
+				return
+			}
+		}
+
+		if err := r.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Facets returns the facet results, which are only available once all hits have
+// been read.
+func (r *SearchResult) Facets() (map[string]SearchFacetResult, error) {
+	if !r.finished {
+		return nil, ErrResultNotFinished
+	}
+
+	facets := make(map[string]SearchFacetResult, len(r.facets))
+	for name, facet := range r.facets {
+		facets[name] = searchFacetFromProto(facet)
+	}
+
+	return facets, nil
+}
+
+// MetaData returns the metadata of the search, which is only available once all
+// hits have been read.
+func (r *SearchResult) MetaData() (*SearchMetaData, error) {
+	if !r.finished {
+		return nil, ErrResultNotFinished
+	}
+	if r.meta == nil {
+		return nil, ErrNoResult
+	}
+
+	meta := &SearchMetaData{
+		Errors: r.meta.Errors,
+	}
+	if metrics := r.meta.Metrics; metrics != nil {
+		meta.Metrics = SearchMetrics{
+			Took:                  metrics.ExecutionTime.AsDuration(),
+			TotalRows:             metrics.TotalRows,
+			MaxScore:              metrics.MaxScore,
+			TotalPartitionCount:   metrics.TotalPartitionCount,
+			SuccessPartitionCount: metrics.SuccessPartitionCount,
+			ErrorPartitionCount:   metrics.ErrorPartitionCount,
+		}
+	}
+
+	return meta, nil
+}
+
+func searchRowFromProto(hit *search_v1.SearchQueryResponse_SearchQueryRow) *SearchRow {
+	row := &SearchRow{
+		Index:       hit.Index,
+		ID:          hit.Id,
+		Score:       hit.Score,
+		Explanation: hit.Explanation,
+		fields:      hit.Fields,
+	}
+
+	for _, location := range hit.Locations {
+		row.Locations = append(row.Locations, SearchRowLocation{
+			Field:          location.Field,
+			Term:           location.Term,
+			Position:       location.Position,
+			Start:          location.Start,
+			End:            location.End,
+			ArrayPositions: location.ArrayPositions,
+		})
+	}
+
+	if len(hit.Fragments) > 0 {
+		row.Fragments = make(map[string][]string, len(hit.Fragments))
+		for field, fragment := range hit.Fragments {
+			row.Fragments[field] = fragment.Content
+		}
+	}
+
+	return row
+}
+
+func searchFacetFromProto(facet *search_v1.SearchQueryResponse_FacetResult) SearchFacetResult {
+	var result SearchFacetResult
+
+	switch f := facet.SearchFacet.(type) {
+	case *search_v1.SearchQueryResponse_FacetResult_TermFacet:
+		result.Field = f.TermFacet.Field
+		result.Total = f.TermFacet.Total
+		result.Missing = f.TermFacet.Missing
+		result.Other = f.TermFacet.Other
+		for _, term := range f.TermFacet.Terms {
+			result.Terms = append(result.Terms, SearchTermFacetResult{
+				Term:  term.Name,
+				Count: term.Size,
+			})
+		}
+	case *search_v1.SearchQueryResponse_FacetResult_NumericRangeFacet:
+		result.Field = f.NumericRangeFacet.Field
+		result.Total = f.NumericRangeFacet.Total
+		result.Missing = f.NumericRangeFacet.Missing
+		result.Other = f.NumericRangeFacet.Other
+		for _, r := range f.NumericRangeFacet.NumericRanges {
+			result.NumericRanges = append(result.NumericRanges, SearchNumericRangeFacetResult{
+				Name:  r.Name,
+				Min:   r.Min,
+				Max:   r.Max,
+				Count: r.Size,
+			})
+		}
+	case *search_v1.SearchQueryResponse_FacetResult_DateRangeFacet:
+		result.Field = f.DateRangeFacet.Field
+		result.Total = f.DateRangeFacet.Total
+		result.Missing = f.DateRangeFacet.Missing
+		result.Other = f.DateRangeFacet.Other
+		for _, r := range f.DateRangeFacet.DateRanges {
+			dateRange := SearchDateRangeFacetResult{
+				Name:  r.Name,
+				Count: r.Size,
+			}
+			if r.Start != nil {
+				dateRange.Start = r.Start.AsTime()
+			}
+			if r.End != nil {
+				dateRange.End = r.End.AsTime()
+			}
+			result.DateRanges = append(result.DateRanges, dateRange)
+		}
+	}
+
+	return result
+}
+
+func executeSearch(ctx context.Context, client *RoutingClient, req *search_v1.SearchQueryRequest) (*SearchResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := client.SearchV1().SearchQuery(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return newSearchResult(stream, cancel), nil
+}
+
+// Search executes a search against a cluster level index.
+func (c *Cluster) Search(ctx context.Context, indexName string, query SearchQuery, opts *SearchOptions) (*SearchResult, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+
+	req, err := opts.toRequest(indexName, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return executeSearch(ctx, c.client, req)
+}
+
+// Search executes a search against an index belonging to this scope.
+func (s *Scope) Search(ctx context.Context, indexName string, query SearchQuery, opts *SearchOptions) (*SearchResult, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+
+	req, err := opts.toRequest(indexName, query)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName := s.BucketName()
+	scopeName := s.name
+	req.BucketName = &bucketName
+	req.ScopeName = &scopeName
+
+	return executeSearch(ctx, s.bucket.cluster.client, req)
+}
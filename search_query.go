@@ -0,0 +1,753 @@
+package gocbcoreps
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/couchbase/goprotostellar/genproto/search_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// SearchQuery is a full-text search query, queries can be combined using
+// ConjunctionQuery, DisjunctionQuery and BooleanQuery.
+type SearchQuery interface {
+	toProto() *search_v1.Query
+}
+
+// cloneMessage copies a builder's message, so that the request built from a query
+// is not changed by later calls to the builder, nor shared between requests.
+func cloneMessage[T proto.Message](m T) T {
+	return proto.Clone(m).(T)
+}
+
+// GeoPoint is a location used by geo queries and sorts.
+type GeoPoint struct {
+	Lat float64
+	Lon float64
+}
+
+func (p GeoPoint) toProto() *search_v1.LatLng {
+	return &search_v1.LatLng{
+		Latitude:  p.Lat,
+		Longitude: p.Lon,
+	}
+}
+
+func searchQueriesToProto(queries []SearchQuery) []*search_v1.Query {
+	out := make([]*search_v1.Query, len(queries))
+	for i, query := range queries {
+		out[i] = query.toProto()
+	}
+	return out
+}
+
+// MatchQuery analyzes the input text and searches for the resulting terms.
+type MatchQuery struct {
+	q search_v1.MatchQuery
+}
+
+func NewMatchQuery(match string) *MatchQuery {
+	return &MatchQuery{q: search_v1.MatchQuery{Value: match}}
+}
+
+func (q *MatchQuery) Field(field string) *MatchQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *MatchQuery) Analyzer(analyzer string) *MatchQuery {
+	q.q.Analyzer = &analyzer
+	return q
+}
+
+func (q *MatchQuery) Fuzziness(fuzziness uint64) *MatchQuery {
+	q.q.Fuzziness = &fuzziness
+	return q
+}
+
+func (q *MatchQuery) PrefixLength(length uint64) *MatchQuery {
+	q.q.PrefixLength = &length
+	return q
+}
+
+// Operator sets whether all (AND) or any (OR) of the analyzed terms must match.
+func (q *MatchQuery) Operator(op search_v1.MatchQuery_Operator) *MatchQuery {
+	q.q.Operator = &op
+	return q
+}
+
+func (q *MatchQuery) Boost(boost float32) *MatchQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *MatchQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_MatchQuery{MatchQuery: cloneMessage(&q.q)}}
+}
+
+// MatchPhraseQuery analyzes the input text and searches for the resulting terms in
+// the same order.
+type MatchPhraseQuery struct {
+	q search_v1.MatchPhraseQuery
+}
+
+func NewMatchPhraseQuery(phrase string) *MatchPhraseQuery {
+	return &MatchPhraseQuery{q: search_v1.MatchPhraseQuery{Phrase: phrase}}
+}
+
+func (q *MatchPhraseQuery) Field(field string) *MatchPhraseQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *MatchPhraseQuery) Analyzer(analyzer string) *MatchPhraseQuery {
+	q.q.Analyzer = &analyzer
+	return q
+}
+
+func (q *MatchPhraseQuery) Boost(boost float32) *MatchPhraseQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *MatchPhraseQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_MatchPhraseQuery{MatchPhraseQuery: cloneMessage(&q.q)}}
+}
+
+// PhraseQuery searches for the exact terms, without analysis, in the same order.
+type PhraseQuery struct {
+	q search_v1.PhraseQuery
+}
+
+func NewPhraseQuery(terms ...string) *PhraseQuery {
+	return &PhraseQuery{q: search_v1.PhraseQuery{Terms: terms}}
+}
+
+func (q *PhraseQuery) Field(field string) *PhraseQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *PhraseQuery) Boost(boost float32) *PhraseQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *PhraseQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_PhraseQuery{PhraseQuery: cloneMessage(&q.q)}}
+}
+
+// TermQuery searches for an exact term, without analysis.
+type TermQuery struct {
+	q search_v1.TermQuery
+}
+
+func NewTermQuery(term string) *TermQuery {
+	return &TermQuery{q: search_v1.TermQuery{Term: term}}
+}
+
+func (q *TermQuery) Field(field string) *TermQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *TermQuery) Fuzziness(fuzziness uint64) *TermQuery {
+	q.q.Fuzziness = &fuzziness
+	return q
+}
+
+func (q *TermQuery) PrefixLength(length uint64) *TermQuery {
+	q.q.PrefixLength = &length
+	return q
+}
+
+func (q *TermQuery) Boost(boost float32) *TermQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *TermQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_TermQuery{TermQuery: cloneMessage(&q.q)}}
+}
+
+// TermRangeQuery searches for terms within a lexical range.
+type TermRangeQuery struct {
+	q search_v1.TermRangeQuery
+}
+
+func NewTermRangeQuery(field string) *TermRangeQuery {
+	return &TermRangeQuery{q: search_v1.TermRangeQuery{Field: &field}}
+}
+
+func (q *TermRangeQuery) Min(min string, inclusive bool) *TermRangeQuery {
+	q.q.Min = &min
+	q.q.InclusiveMin = &inclusive
+	return q
+}
+
+func (q *TermRangeQuery) Max(max string, inclusive bool) *TermRangeQuery {
+	q.q.Max = &max
+	q.q.InclusiveMax = &inclusive
+	return q
+}
+
+func (q *TermRangeQuery) Boost(boost float32) *TermRangeQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *TermRangeQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_TermRangeQuery{TermRangeQuery: cloneMessage(&q.q)}}
+}
+
+// PrefixQuery searches for terms which start with prefix.
+type PrefixQuery struct {
+	q search_v1.PrefixQuery
+}
+
+func NewPrefixQuery(prefix string) *PrefixQuery {
+	return &PrefixQuery{q: search_v1.PrefixQuery{Prefix: prefix}}
+}
+
+func (q *PrefixQuery) Field(field string) *PrefixQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *PrefixQuery) Boost(boost float32) *PrefixQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *PrefixQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_PrefixQuery{PrefixQuery: cloneMessage(&q.q)}}
+}
+
+// RegexpQuery searches for terms which match a regular expression.
+type RegexpQuery struct {
+	q search_v1.RegexpQuery
+}
+
+func NewRegexpQuery(regexp string) *RegexpQuery {
+	return &RegexpQuery{q: search_v1.RegexpQuery{Regexp: regexp}}
+}
+
+func (q *RegexpQuery) Field(field string) *RegexpQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *RegexpQuery) Boost(boost float32) *RegexpQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *RegexpQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_RegexpQuery{RegexpQuery: cloneMessage(&q.q)}}
+}
+
+// WildcardQuery searches for terms which match a pattern containing * and ?.
+type WildcardQuery struct {
+	q search_v1.WildcardQuery
+}
+
+func NewWildcardQuery(wildcard string) *WildcardQuery {
+	return &WildcardQuery{q: search_v1.WildcardQuery{Wildcard: wildcard}}
+}
+
+func (q *WildcardQuery) Field(field string) *WildcardQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *WildcardQuery) Boost(boost float32) *WildcardQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *WildcardQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_WildcardQuery{WildcardQuery: cloneMessage(&q.q)}}
+}
+
+// QueryStringQuery searches using the search service's query string syntax.
+type QueryStringQuery struct {
+	q search_v1.QueryStringQuery
+}
+
+func NewQueryStringQuery(query string) *QueryStringQuery {
+	return &QueryStringQuery{q: search_v1.QueryStringQuery{QueryString: query}}
+}
+
+func (q *QueryStringQuery) Boost(boost float32) *QueryStringQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *QueryStringQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_QueryStringQuery{QueryStringQuery: cloneMessage(&q.q)}}
+}
+
+// NumericRangeQuery searches for numeric values within a range.
+type NumericRangeQuery struct {
+	q search_v1.NumericRangeQuery
+}
+
+func NewNumericRangeQuery(field string) *NumericRangeQuery {
+	return &NumericRangeQuery{q: search_v1.NumericRangeQuery{Field: &field}}
+}
+
+func (q *NumericRangeQuery) Min(min float32, inclusive bool) *NumericRangeQuery {
+	q.q.Min = &min
+	q.q.InclusiveMin = &inclusive
+	return q
+}
+
+func (q *NumericRangeQuery) Max(max float32, inclusive bool) *NumericRangeQuery {
+	q.q.Max = &max
+	q.q.InclusiveMax = &inclusive
+	return q
+}
+
+func (q *NumericRangeQuery) Boost(boost float32) *NumericRangeQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *NumericRangeQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_NumericRangeQuery{NumericRangeQuery: cloneMessage(&q.q)}}
+}
+
+// DateRangeQuery searches for dates within a range. Dates are parsed using the
+// date time parser, which by default expects RFC3339.
+type DateRangeQuery struct {
+	q search_v1.DateRangeQuery
+}
+
+func NewDateRangeQuery(field string) *DateRangeQuery {
+	return &DateRangeQuery{q: search_v1.DateRangeQuery{Field: &field}}
+}
+
+func (q *DateRangeQuery) Start(start string) *DateRangeQuery {
+	q.q.StartDate = &start
+	return q
+}
+
+func (q *DateRangeQuery) End(end string) *DateRangeQuery {
+	q.q.EndDate = &end
+	return q
+}
+
+func (q *DateRangeQuery) DateTimeParser(parser string) *DateRangeQuery {
+	q.q.DateTimeParser = &parser
+	return q
+}
+
+func (q *DateRangeQuery) Boost(boost float32) *DateRangeQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *DateRangeQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_DateRangeQuery{DateRangeQuery: cloneMessage(&q.q)}}
+}
+
+// ConjunctionQuery matches documents which match all of its queries.
+type ConjunctionQuery struct {
+	queries []SearchQuery
+	boost   *float32
+}
+
+func NewConjunctionQuery(queries ...SearchQuery) *ConjunctionQuery {
+	return &ConjunctionQuery{queries: queries}
+}
+
+func (q *ConjunctionQuery) And(queries ...SearchQuery) *ConjunctionQuery {
+	q.queries = append(q.queries, queries...)
+	return q
+}
+
+func (q *ConjunctionQuery) Boost(boost float32) *ConjunctionQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *ConjunctionQuery) conjunctionProto() *search_v1.ConjunctionQuery {
+	return &search_v1.ConjunctionQuery{
+		Boost:   q.boost,
+		Queries: searchQueriesToProto(q.queries),
+	}
+}
+
+func (q *ConjunctionQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_ConjunctionQuery{ConjunctionQuery: q.conjunctionProto()}}
+}
+
+// DisjunctionQuery matches documents which match at least Min of its queries, by
+// default one.
+type DisjunctionQuery struct {
+	queries []SearchQuery
+	boost   *float32
+	min     *uint32
+}
+
+func NewDisjunctionQuery(queries ...SearchQuery) *DisjunctionQuery {
+	return &DisjunctionQuery{queries: queries}
+}
+
+func (q *DisjunctionQuery) Or(queries ...SearchQuery) *DisjunctionQuery {
+	q.queries = append(q.queries, queries...)
+	return q
+}
+
+func (q *DisjunctionQuery) Min(min uint32) *DisjunctionQuery {
+	q.min = &min
+	return q
+}
+
+func (q *DisjunctionQuery) Boost(boost float32) *DisjunctionQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *DisjunctionQuery) disjunctionProto() *search_v1.DisjunctionQuery {
+	return &search_v1.DisjunctionQuery{
+		Boost:   q.boost,
+		Queries: searchQueriesToProto(q.queries),
+		Minimum: q.min,
+	}
+}
+
+func (q *DisjunctionQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_DisjunctionQuery{DisjunctionQuery: q.disjunctionProto()}}
+}
+
+// BooleanQuery matches documents which match all Must queries, at least one Should
+// query, and none of the MustNot queries.
+type BooleanQuery struct {
+	must    *ConjunctionQuery
+	mustNot *DisjunctionQuery
+	should  *DisjunctionQuery
+	boost   *float32
+}
+
+func NewBooleanQuery() *BooleanQuery {
+	return &BooleanQuery{}
+}
+
+func (q *BooleanQuery) Must(queries ...SearchQuery) *BooleanQuery {
+	if q.must == nil {
+		q.must = NewConjunctionQuery()
+	}
+	q.must.And(queries...)
+	return q
+}
+
+func (q *BooleanQuery) MustNot(queries ...SearchQuery) *BooleanQuery {
+	if q.mustNot == nil {
+		q.mustNot = NewDisjunctionQuery()
+	}
+	q.mustNot.Or(queries...)
+	return q
+}
+
+func (q *BooleanQuery) Should(queries ...SearchQuery) *BooleanQuery {
+	if q.should == nil {
+		q.should = NewDisjunctionQuery()
+	}
+	q.should.Or(queries...)
+	return q
+}
+
+// ShouldMin sets how many of the Should queries must match.
+func (q *BooleanQuery) ShouldMin(min uint32) *BooleanQuery {
+	if q.should == nil {
+		q.should = NewDisjunctionQuery()
+	}
+	q.should.Min(min)
+	return q
+}
+
+func (q *BooleanQuery) Boost(boost float32) *BooleanQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *BooleanQuery) toProto() *search_v1.Query {
+	query := &search_v1.BooleanQuery{Boost: q.boost}
+	if q.must != nil {
+		query.Must = q.must.conjunctionProto()
+	}
+	if q.mustNot != nil {
+		query.MustNot = q.mustNot.disjunctionProto()
+	}
+	if q.should != nil {
+		query.Should = q.should.disjunctionProto()
+	}
+
+	return &search_v1.Query{Query: &search_v1.Query_BooleanQuery{BooleanQuery: query}}
+}
+
+// BooleanFieldQuery matches documents where a boolean field has the given value.
+type BooleanFieldQuery struct {
+	q search_v1.BooleanFieldQuery
+}
+
+func NewBooleanFieldQuery(value bool) *BooleanFieldQuery {
+	return &BooleanFieldQuery{q: search_v1.BooleanFieldQuery{Value: value}}
+}
+
+func (q *BooleanFieldQuery) Field(field string) *BooleanFieldQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *BooleanFieldQuery) Boost(boost float32) *BooleanFieldQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *BooleanFieldQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_BooleanFieldQuery{BooleanFieldQuery: cloneMessage(&q.q)}}
+}
+
+// DocIDQuery matches documents with the given ids.
+type DocIDQuery struct {
+	q search_v1.DocIdQuery
+}
+
+func NewDocIDQuery(ids ...string) *DocIDQuery {
+	return &DocIDQuery{q: search_v1.DocIdQuery{Ids: ids}}
+}
+
+func (q *DocIDQuery) Boost(boost float32) *DocIDQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *DocIDQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_DocIdQuery{DocIdQuery: cloneMessage(&q.q)}}
+}
+
+// MatchAllQuery matches every document.
+type MatchAllQuery struct{}
+
+func NewMatchAllQuery() *MatchAllQuery {
+	return &MatchAllQuery{}
+}
+
+func (q *MatchAllQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_MatchAllQuery{MatchAllQuery: &search_v1.MatchAllQuery{}}}
+}
+
+// MatchNoneQuery matches no documents.
+type MatchNoneQuery struct{}
+
+func NewMatchNoneQuery() *MatchNoneQuery {
+	return &MatchNoneQuery{}
+}
+
+func (q *MatchNoneQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_MatchNoneQuery{MatchNoneQuery: &search_v1.MatchNoneQuery{}}}
+}
+
+// GeoDistanceQuery matches locations within a distance, such as "10mi", of a point.
+type GeoDistanceQuery struct {
+	q search_v1.GeoDistanceQuery
+}
+
+func NewGeoDistanceQuery(center GeoPoint, distance string) *GeoDistanceQuery {
+	return &GeoDistanceQuery{q: search_v1.GeoDistanceQuery{
+		Center:   center.toProto(),
+		Distance: distance,
+	}}
+}
+
+func (q *GeoDistanceQuery) Field(field string) *GeoDistanceQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *GeoDistanceQuery) Boost(boost float32) *GeoDistanceQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *GeoDistanceQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_GeoDistanceQuery{GeoDistanceQuery: cloneMessage(&q.q)}}
+}
+
+// GeoBoundingBoxQuery matches locations within a rectangle.
+type GeoBoundingBoxQuery struct {
+	q search_v1.GeoBoundingBoxQuery
+}
+
+func NewGeoBoundingBoxQuery(topLeft, bottomRight GeoPoint) *GeoBoundingBoxQuery {
+	return &GeoBoundingBoxQuery{q: search_v1.GeoBoundingBoxQuery{
+		TopLeft:     topLeft.toProto(),
+		BottomRight: bottomRight.toProto(),
+	}}
+}
+
+func (q *GeoBoundingBoxQuery) Field(field string) *GeoBoundingBoxQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *GeoBoundingBoxQuery) Boost(boost float32) *GeoBoundingBoxQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *GeoBoundingBoxQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_GeoBoundingBoxQuery{GeoBoundingBoxQuery: cloneMessage(&q.q)}}
+}
+
+// GeoPolygonQuery matches locations within a polygon.
+type GeoPolygonQuery struct {
+	q search_v1.GeoPolygonQuery
+}
+
+func NewGeoPolygonQuery(vertices ...GeoPoint) *GeoPolygonQuery {
+	points := make([]*search_v1.LatLng, len(vertices))
+	for i, vertex := range vertices {
+		points[i] = vertex.toProto()
+	}
+
+	return &GeoPolygonQuery{q: search_v1.GeoPolygonQuery{Vertices: points}}
+}
+
+func (q *GeoPolygonQuery) Field(field string) *GeoPolygonQuery {
+	q.q.Field = &field
+	return q
+}
+
+func (q *GeoPolygonQuery) Boost(boost float32) *GeoPolygonQuery {
+	q.q.Boost = &boost
+	return q
+}
+
+func (q *GeoPolygonQuery) toProto() *search_v1.Query {
+	return &search_v1.Query{Query: &search_v1.Query_GeoPolygonQuery{GeoPolygonQuery: cloneMessage(&q.q)}}
+}
+
+const defaultVectorQueryK = 3
+
+// VectorQuery is a k nearest neighbours query against a vector field.
+type VectorQuery struct {
+	field  string
+	vector []float32
+	k      int64
+	boost  *float32
+	err    error
+}
+
+// NewVectorQuery creates a query for the k nearest neighbours of vector, by default
+// k is 3.
+func NewVectorQuery(field string, vector []float32) *VectorQuery {
+	return &VectorQuery{
+		field:  field,
+		vector: vector,
+		k:      defaultVectorQueryK,
+	}
+}
+
+// NumCandidates sets k, the number of nearest neighbours which are returned.
+func (q *VectorQuery) NumCandidates(k int64) *VectorQuery {
+	q.k = k
+	return q
+}
+
+func (q *VectorQuery) Boost(boost float32) *VectorQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *VectorQuery) validate() error {
+	if q.err != nil {
+		return fmt.Errorf("vector query on field '%s': %w", q.field, q.err)
+	}
+	if len(q.vector) == 0 {
+		return fmt.Errorf("%w: vector query on field '%s' has no vector", ErrInvalidVector, q.field)
+	}
+	if q.k <= 0 {
+		return fmt.Errorf("%w: vector query on field '%s' must have a positive k", ErrInvalidVector, q.field)
+	}
+	for i, v := range q.vector {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return fmt.Errorf("%w: vector query on field '%s' has a non-finite value at %d", ErrInvalidVector, q.field, i)
+		}
+	}
+
+	return nil
+}
+
+func (q *VectorQuery) knnProto() *search_v1.KnnQuery {
+	return &search_v1.KnnQuery{
+		Field:  q.field,
+		K:      q.k,
+		Vector: q.vector,
+		Boost:  q.boost,
+	}
+}
+
+// VectorQueryCombination controls how the hits of multiple vector queries are
+// combined.
+type VectorQueryCombination int
+
+const (
+	// VectorQueryCombinationOr returns hits matching any of the vector queries.
+	VectorQueryCombinationOr VectorQueryCombination = iota
+
+	// VectorQueryCombinationAnd returns only hits matching all of the vector queries.
+	VectorQueryCombinationAnd
+)
+
+// VectorSearch combines one or more vector queries, it is sent alongside any
+// full-text query in a search request.
+type VectorSearch struct {
+	queries     []*VectorQuery
+	combination *VectorQueryCombination
+}
+
+func NewVectorSearch(queries ...*VectorQuery) *VectorSearch {
+	return &VectorSearch{queries: queries}
+}
+
+// Combination sets how the hits of the vector queries are combined with each other,
+// by default they are ORed.
+func (s *VectorSearch) Combination(combination VectorQueryCombination) *VectorSearch {
+	s.combination = &combination
+	return s
+}
+
+func (s *VectorSearch) totalK() int64 {
+	var total int64
+	for _, query := range s.queries {
+		total += query.k
+	}
+	return total
+}
+
+func (s *VectorSearch) apply(req *search_v1.SearchQueryRequest) error {
+	if len(s.queries) == 0 {
+		return fmt.Errorf("%w: vector search has no queries", ErrInvalidVector)
+	}
+
+	for _, query := range s.queries {
+		if err := query.validate(); err != nil {
+			return err
+		}
+
+		req.Knn = append(req.Knn, query.knnProto())
+	}
+
+	if s.combination != nil {
+		op := search_v1.KnnOperator_KNN_OPERATOR_OR
+		if *s.combination == VectorQueryCombinationAnd {
+			op = search_v1.KnnOperator_KNN_OPERATOR_AND
+		}
+		req.KnnOperator = &op
+	}
+
+	return nil
+}
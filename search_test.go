@@ -0,0 +1,274 @@
+package gocbcoreps
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/couchbase/goprotostellar/genproto/search_v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeSearchStream struct {
+	grpc.ClientStream
+
+	resps []*search_v1.SearchQueryResponse
+	err   error
+}
+
+func (s *fakeSearchStream) Recv() (*search_v1.SearchQueryResponse, error) {
+	if len(s.resps) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+
+	resp := s.resps[0]
+	s.resps = s.resps[1:]
+	return resp, nil
+}
+
+func TestSearchOptionsToRequest(t *testing.T) {
+	query := NewMatchQuery("hotel").Field("type")
+	opts := &SearchOptions{
+		Limit:     10,
+		Skip:      5,
+		Explain:   true,
+		Fields:    []string{"name"},
+		Highlight: &SearchHighlight{Style: search_v1.SearchQueryRequest_HIGHLIGHT_STYLE_HTML, Fields: []string{"name"}},
+		Sort:      []SearchSort{SearchSortScore{Descending: true}, SearchSortField{Field: "name"}},
+		Facets:    map[string]SearchFacet{"types": SearchTermFacet{Field: "type", Size: 3}},
+	}
+
+	req, err := opts.toRequest("idx", query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &search_v1.SearchQueryRequest{
+		IndexName:          "idx",
+		Query:              query.toProto(),
+		Limit:              10,
+		Skip:               5,
+		IncludeExplanation: true,
+		Fields:             []string{"name"},
+		HighlightStyle:     search_v1.SearchQueryRequest_HIGHLIGHT_STYLE_HTML,
+		HighlightFields:    []string{"name"},
+		Sort: []*search_v1.Sorting{
+			{Sorting: &search_v1.Sorting_ScoreSorting{ScoreSorting: &search_v1.ScoreSorting{Descending: true}}},
+			{Sorting: &search_v1.Sorting_FieldSorting{FieldSorting: &search_v1.FieldSorting{Field: "name"}}},
+		},
+		Facets: map[string]*search_v1.Facet{
+			"types": {Facet: &search_v1.Facet_TermFacet{TermFacet: &search_v1.TermFacet{Field: "type", Size: 3}}},
+		},
+	}
+	if !proto.Equal(req, expected) {
+		t.Fatalf("expected %v, got %v", expected, req)
+	}
+}
+
+func TestSearchOptionsToRequestVectorSearch(t *testing.T) {
+	opts := &SearchOptions{
+		VectorSearch: NewVectorSearch(
+			NewVectorQuery("vec", []float32{1, 2}).NumCandidates(5),
+			NewVectorQuery("vec2", []float32{3}).Boost(2),
+		).Combination(VectorQueryCombinationAnd),
+	}
+
+	req, err := opts.toRequest("idx", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !proto.Equal(req.Query, NewMatchNoneQuery().toProto()) {
+		t.Fatalf("expected a vector only search to match no full-text hits, got %v", req.Query)
+	}
+	if len(req.Knn) != 2 || req.Knn[0].K != 5 || req.Knn[1].K != defaultVectorQueryK || req.Knn[1].GetBoost() != 2 {
+		t.Fatalf("unexpected knn queries %v", req.Knn)
+	}
+	if req.GetKnnOperator() != search_v1.KnnOperator_KNN_OPERATOR_AND {
+		t.Fatalf("expected the AND knn operator, got %v", req.GetKnnOperator())
+	}
+
+	_, err = (&SearchOptions{VectorSearch: NewVectorSearch()}).toRequest("idx", nil)
+	if !errors.Is(err, ErrInvalidVector) {
+		t.Fatalf("expected ErrInvalidVector for an empty vector search, got %v", err)
+	}
+}
+
+func TestSearchQueryToProto(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    SearchQuery
+		expected *search_v1.Query
+	}{
+		{
+			name:  "term",
+			query: NewTermQuery("hotel").Field("type"),
+			expected: &search_v1.Query{Query: &search_v1.Query_TermQuery{
+				TermQuery: &search_v1.TermQuery{Term: "hotel", Field: proto.String("type")},
+			}},
+		},
+		{
+			name:  "conjunction",
+			query: NewConjunctionQuery(NewMatchAllQuery()).And(NewMatchNoneQuery()),
+			expected: &search_v1.Query{Query: &search_v1.Query_ConjunctionQuery{
+				ConjunctionQuery: &search_v1.ConjunctionQuery{Queries: []*search_v1.Query{
+					NewMatchAllQuery().toProto(),
+					NewMatchNoneQuery().toProto(),
+				}},
+			}},
+		},
+		{
+			name:  "doc ids",
+			query: NewDocIDQuery("a", "b"),
+			expected: &search_v1.Query{Query: &search_v1.Query_DocIdQuery{
+				DocIdQuery: &search_v1.DocIdQuery{Ids: []string{"a", "b"}},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if q := test.query.toProto(); !proto.Equal(q, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, q)
+			}
+		})
+	}
+}
+
+func TestSearchQueryToProtoDoesNotAlias(t *testing.T) {
+	match := NewMatchQuery("hello").Field("a")
+	term := NewTermQuery("hello").Field("a")
+	docIDs := NewDocIDQuery("a", "b")
+
+	tests := []struct {
+		name   string
+		query  SearchQuery
+		modify func()
+	}{
+		{"match", match, func() { match.Field("b").Boost(2) }},
+		{"term", term, func() { term.Field("b").Fuzziness(1) }},
+		{"doc id", docIDs, func() { docIDs.Boost(3) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.query.toProto()
+			before := proto.Clone(first)
+
+			// A later change to the builder must not alter a request already built.
+			tt.modify()
+
+			if !proto.Equal(first, before) {
+				t.Fatalf("expected %v to be unchanged, got %v", before, first)
+			}
+			if proto.Equal(tt.query.toProto(), before) {
+				t.Fatalf("expected the builder change to apply to later requests")
+			}
+		})
+	}
+}
+
+func TestSearchResult(t *testing.T) {
+	stream := &fakeSearchStream{resps: []*search_v1.SearchQueryResponse{
+		{
+			Hits: []*search_v1.SearchQueryResponse_SearchQueryRow{
+				{
+					Id:     "a",
+					Score:  2,
+					Fields: map[string][]byte{"name": []byte(`"A"`)},
+					Fragments: map[string]*search_v1.SearchQueryResponse_Fragment{
+						"name": {Content: []string{"<mark>A</mark>"}},
+					},
+				},
+			},
+		},
+		{
+			Hits: []*search_v1.SearchQueryResponse_SearchQueryRow{{Id: "b", Score: 1}},
+			Facets: map[string]*search_v1.SearchQueryResponse_FacetResult{
+				"types": {SearchFacet: &search_v1.SearchQueryResponse_FacetResult_TermFacet{
+					TermFacet: &search_v1.SearchQueryResponse_TermFacetResult{
+						Field: "type",
+						Total: 2,
+						Terms: []*search_v1.SearchQueryResponse_TermResult{{Name: "hotel", Size: 2}},
+					},
+				}},
+			},
+		},
+		{
+			MetaData: &search_v1.SearchQueryResponse_MetaData{
+				Metrics: &search_v1.SearchQueryResponse_SearchMetrics{TotalRows: 2},
+			},
+		},
+	}}
+
+	r := NewSearchResult(stream)
+	if _, err := r.Facets(); !errors.Is(err, ErrResultNotFinished) {
+		t.Fatalf("expected ErrResultNotFinished, got %v", err)
+	}
+
+	var ids []string
+	for hit, err := range r.Rows() {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, hit.ID)
+
+		if hit.ID == "a" {
+			var fields struct{ Name string }
+			if err := hit.Fields(&fields); err != nil || fields.Name != "A" {
+				t.Fatalf("expected fields to decode, got %v, %v", fields, err)
+			}
+			if hit.Fragments["name"][0] != "<mark>A</mark>" {
+				t.Fatalf("expected fragments, got %v", hit.Fragments)
+			}
+		}
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("expected hits [a b], got %v", ids)
+	}
+
+	facets, err := r.Facets()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if facet := facets["types"]; facet.Total != 2 || len(facet.Terms) != 1 || facet.Terms[0].Term != "hotel" {
+		t.Fatalf("unexpected facet %v", facet)
+	}
+
+	meta, err := r.MetaData()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.Metrics.TotalRows != 2 {
+		t.Fatalf("expected total rows 2, got %d", meta.Metrics.TotalRows)
+	}
+}
+
+func TestSearchResultStreamError(t *testing.T) {
+	streamErr := errors.New("stream failed")
+	r := NewSearchResult(&fakeSearchStream{
+		resps: []*search_v1.SearchQueryResponse{{Hits: []*search_v1.SearchQueryResponse_SearchQueryRow{{Id: "a"}}}},
+		err:   streamErr,
+	})
+
+	var errs []error
+	for hit, err := range r.Rows() {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if hit.ID != "a" {
+			t.Fatalf("unexpected hit %v", hit.ID)
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], streamErr) {
+		t.Fatalf("expected the stream error to be yielded last, got %v", errs)
+	}
+	if r.Row() != nil {
+		t.Fatalf("expected no current hit once closed")
+	}
+}
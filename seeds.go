@@ -0,0 +1,157 @@
+package gocbcoreps
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+const defaultSeedPort = "18098"
+
+const seedResolverScheme = "couchbase2-seeds"
+
+// When dialing a single hostname we let grpc resolve it, shuffling the resolved
+// addresses so that the connections in our pool are spread across them.
+const shuffledPickFirstServiceConfig = `{"loadBalancingConfig": [{"pick_first": {"shuffleAddressList": true}}]}`
+
+// parseSeeds splits a comma separated list of seed addresses.
+func parseSeeds(target string) []string {
+	var seeds []string
+	for _, seed := range strings.Split(target, ",") {
+		seed = strings.TrimSpace(seed)
+		if seed == "" {
+			continue
+		}
+
+		seeds = append(seeds, seed)
+	}
+
+	return seeds
+}
+
+// normalizeSeeds validates the seeds, applying the default port to any seed which
+// does not specify one. A seed with an explicit scheme is handled by its own
+// resolver, so it cannot be combined with other seeds.
+func normalizeSeeds(seeds []string) ([]string, error) {
+	if len(seeds) == 0 {
+		return nil, ErrNoSeeds
+	}
+
+	normalized := make([]string, len(seeds))
+	for i, seed := range seeds {
+		seed = strings.TrimSpace(seed)
+		if strings.Contains(seed, "://") {
+			if len(seeds) > 1 {
+				return nil, fmt.Errorf("%w: %q has a scheme and cannot be combined with other seeds", ErrInvalidSeed, seed)
+			}
+
+			normalized[i] = seed
+			continue
+		}
+
+		seed, err := seedWithDefaultPort(seed)
+		if err != nil {
+			return nil, err
+		}
+
+		normalized[i] = seed
+	}
+
+	return normalized, nil
+}
+
+func seedWithDefaultPort(seed string) (string, error) {
+	if seed == "" {
+		return "", fmt.Errorf("%w: empty seed", ErrInvalidSeed)
+	}
+
+	// A hostname, IPv4 address or bracketed IPv6 address without a port.
+	bracketed := strings.HasPrefix(seed, "[") && strings.HasSuffix(seed, "]")
+	if !strings.Contains(seed, ":") || bracketed || net.ParseIP(seed) != nil {
+		host := strings.Trim(seed, "[]")
+		if host == "" {
+			return "", fmt.Errorf("%w: %q has no host", ErrInvalidSeed, seed)
+		}
+
+		return net.JoinHostPort(host, defaultSeedPort), nil
+	}
+
+	host, port, err := net.SplitHostPort(seed)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %w", ErrInvalidSeed, seed, err)
+	}
+	if host == "" {
+		return "", fmt.Errorf("%w: %q has no host", ErrInvalidSeed, seed)
+	}
+	if portNum, err := strconv.Atoi(port); err != nil || portNum <= 0 || portNum > 65535 {
+		return "", fmt.Errorf("%w: %q has an invalid port", ErrInvalidSeed, seed)
+	}
+
+	return seed, nil
+}
+
+// seedDialTarget returns the target and dial options used to connect to the given
+// seeds, which must already have been normalized. The offset rotates the order in
+// which seeds are tried, so that each connection in the pool prefers a different
+// seed but fails over to the others. The returned refresher, if any, can force the
+// target to be re-resolved, static seed lists have none.
+//
+// When remoteDNS is set, such as when dialling through a proxy, hostnames are never
+// resolved locally so that the dialer receives the unresolved host:port.
+func seedDialTarget(seeds []string, offset int, remoteDNS bool) (string, []grpc.DialOption, routingRefresher) {
+	if len(seeds) == 1 {
+		if scheme, _, ok := strings.Cut(seeds[0], "://"); ok {
+			// A target with an explicit scheme is handled by its own resolver. Our own
+			// schemes are refreshed through their builders.
+			if scheme == RoutingScheme || scheme == OptimizedRoutingScheme {
+				return seeds[0], nil, nil
+			}
+
+			builder := resolver.Get(scheme)
+			if builder == nil {
+				return seeds[0], nil, nil
+			}
+
+			refreshable := newRefreshableBuilder(builder)
+			return seeds[0], []grpc.DialOption{grpc.WithResolvers(refreshable)}, refreshable
+		}
+
+		if !remoteDNS {
+			refreshable := newRefreshableBuilder(resolver.Get("dns"))
+			return "dns:///" + seeds[0], []grpc.DialOption{
+				grpc.WithDefaultServiceConfig(shuffledPickFirstServiceConfig),
+				grpc.WithResolvers(refreshable),
+			}, refreshable
+		}
+	}
+
+	// The seed list is static and each hostname is resolved when it is dialled, so
+	// there is nothing to re-resolve and no refresher is returned.
+	r := manual.NewBuilderWithScheme(seedResolverScheme)
+	r.InitialState(resolver.State{Addresses: seedAddresses(seeds, offset)})
+
+	return fmt.Sprintf("%s:///%d", seedResolverScheme, offset), []grpc.DialOption{grpc.WithResolvers(r)}, nil
+}
+
+// seedAddresses returns the seeds as resolver addresses, rotated by offset.
+func seedAddresses(seeds []string, offset int) []resolver.Address {
+	addrs := make([]resolver.Address, len(seeds))
+	for i := range seeds {
+		seed := seeds[(i+offset)%len(seeds)]
+		host, _, _ := net.SplitHostPort(seed) // validated by normalizeSeeds
+
+		addrs[i] = resolver.Address{
+			Addr: seed,
+			// Each seed must be verified against its own hostname rather than the
+			// authority of the synthetic target.
+			ServerName: host,
+		}
+	}
+
+	return addrs
+}
@@ -0,0 +1,229 @@
+package gocbcoreps
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSeeds(t *testing.T) {
+	tests := []struct {
+		name     string
+		seeds    []string
+		expected []string
+		err      error
+	}{
+		{
+			name:     "single hostname without port",
+			seeds:    []string{"cb.local"},
+			expected: []string{"cb.local:18098"},
+		},
+		{
+			name:     "single hostname with port",
+			seeds:    []string{"cb.local:1234"},
+			expected: []string{"cb.local:1234"},
+		},
+		{
+			name:     "bare ipv6",
+			seeds:    []string{"::1"},
+			expected: []string{"[::1]:18098"},
+		},
+		{
+			name:     "bracketed ipv6 without port",
+			seeds:    []string{"[::1]"},
+			expected: []string{"[::1]:18098"},
+		},
+		{
+			name:     "bracketed ipv6 with port",
+			seeds:    []string{"[::1]:1234"},
+			expected: []string{"[::1]:1234"},
+		},
+		{
+			name:     "single seed with scheme",
+			seeds:    []string{"couchbase2://cb.local"},
+			expected: []string{"couchbase2://cb.local"},
+		},
+		{
+			name:     "multiple seeds mixing ports",
+			seeds:    []string{"h1", "h2:1234", " 10.0.0.1 "},
+			expected: []string{"h1:18098", "h2:1234", "10.0.0.1:18098"},
+		},
+		{
+			name:  "multiple seeds with a scheme",
+			seeds: []string{"couchbase2://h1", "h2"},
+			err:   ErrInvalidSeed,
+		},
+		{
+			name:  "multiple seeds with dns scheme",
+			seeds: []string{"h1", "dns:///h2"},
+			err:   ErrInvalidSeed,
+		},
+		{
+			name:  "invalid port",
+			seeds: []string{"h1:port"},
+			err:   ErrInvalidSeed,
+		},
+		{
+			name:  "port out of range",
+			seeds: []string{"h1:70000"},
+			err:   ErrInvalidSeed,
+		},
+		{
+			name:  "missing host",
+			seeds: []string{":1234"},
+			err:   ErrInvalidSeed,
+		},
+		{
+			name:  "empty seed",
+			seeds: []string{"h1", ""},
+			err:   ErrInvalidSeed,
+		},
+		{
+			name:  "no seeds",
+			seeds: nil,
+			err:   ErrNoSeeds,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			seeds, err := normalizeSeeds(test.seeds)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected %v, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(seeds, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, seeds)
+			}
+		})
+	}
+}
+
+func TestParseSeeds(t *testing.T) {
+	seeds := parseSeeds(" h1, h2:1234,,h3 ")
+	expected := []string{"h1", "h2:1234", "h3"}
+	if !reflect.DeepEqual(seeds, expected) {
+		t.Fatalf("expected %v, got %v", expected, seeds)
+	}
+}
+
+func TestSeedDialTarget(t *testing.T) {
+	tests := []struct {
+		name         string
+		seeds        []string
+		offset       int
+		remoteDNS    bool
+		targetPrefix string
+		numOpts      int
+		refreshable  bool
+	}{
+		{
+			name:         "single seed",
+			seeds:        []string{"cb.local:18098"},
+			targetPrefix: "dns:///cb.local:18098",
+			numOpts:      2,
+			refreshable:  true,
+		},
+		{
+			name:         "single seed with remote dns",
+			seeds:        []string{"cb.local:18098"},
+			remoteDNS:    true,
+			targetPrefix: seedResolverScheme + ":///0",
+			numOpts:      1,
+		},
+		{
+			name:         "single seed with our scheme",
+			seeds:        []string{"couchbase2://cb.local"},
+			targetPrefix: "couchbase2://cb.local",
+		},
+		{
+			name:         "single seed with dns scheme",
+			seeds:        []string{"dns:///cb.local:18098"},
+			targetPrefix: "dns:///cb.local:18098",
+			numOpts:      1,
+			refreshable:  true,
+		},
+		{
+			name:         "multiple seeds",
+			seeds:        []string{"h1:18098", "h2:18098"},
+			offset:       1,
+			targetPrefix: seedResolverScheme + ":///1",
+			numOpts:      1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target, opts, refresher := seedDialTarget(test.seeds, test.offset, test.remoteDNS)
+			if !strings.HasPrefix(target, test.targetPrefix) {
+				t.Fatalf("expected target %q, got %q", test.targetPrefix, target)
+			}
+			if len(opts) != test.numOpts {
+				t.Fatalf("expected %d dial options, got %d", test.numOpts, len(opts))
+			}
+			if (refresher != nil) != test.refreshable {
+				t.Fatalf("expected refreshable %v, got %v", test.refreshable, refresher != nil)
+			}
+		})
+	}
+}
+
+func TestSeedAddresses(t *testing.T) {
+	seeds := []string{"h1:18098", "[::1]:1234", "h3:18098"}
+
+	addrs := seedAddresses(seeds, 1)
+
+	expectedAddrs := []string{"[::1]:1234", "h3:18098", "h1:18098"}
+	expectedNames := []string{"::1", "h3", "h1"}
+	for i, addr := range addrs {
+		if addr.Addr != expectedAddrs[i] {
+			t.Errorf("expected address %d to be %q, got %q", i, expectedAddrs[i], addr.Addr)
+		}
+		if addr.ServerName != expectedNames[i] {
+			t.Errorf("expected server name %d to be %q, got %q", i, expectedNames[i], addr.ServerName)
+		}
+	}
+}
+
+func TestDialSeedsContextDialerReceivesHostname(t *testing.T) {
+	dialed := make(chan string, 1)
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		select {
+		case dialed <- addr:
+		default:
+		}
+		return nil, errors.New("dial refused by test")
+	}
+
+	client, err := DialSeedsContext(context.Background(), []string{"couchbase.invalid:18098"}, &DialOptions{
+		InsecureTransport: true,
+		ContextDialer:     dialer,
+	})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() { _ = client.WarmUp(ctx) }()
+
+	select {
+	case addr := <-dialed:
+		if addr != "couchbase.invalid:18098" {
+			t.Fatalf("expected dialer to receive the unresolved seed, got %q", addr)
+		}
+	case <-ctx.Done():
+		t.Fatalf("dialer was never called")
+	}
+}
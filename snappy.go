@@ -0,0 +1,33 @@
+package gocbcoreps
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/snappy"
+)
+
+var errSnappyTooLarge = errors.New("snappy: decoded length too large")
+
+// snappyMaxDecodedLen bounds the decoded length taken from the untrusted block
+// header, documents cannot be larger than 20MiB.
+const snappyMaxDecodedLen = 20 * 1024 * 1024
+
+// snappyDecode decodes a snappy block, which is how the server compresses document
+// content.
+func snappyDecode(src []byte) ([]byte, error) {
+	length, err := snappy.DecodedLen(src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+	if length > snappyMaxDecodedLen {
+		return nil, fmt.Errorf("%w: %w", ErrDecodingFailure, errSnappyTooLarge)
+	}
+
+	dst, err := snappy.Decode(nil, src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+
+	return dst, nil
+}
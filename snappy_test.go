@@ -0,0 +1,72 @@
+package gocbcoreps
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/golang/snappy"
+)
+
+func TestSnappyDecodeRoundTrip(t *testing.T) {
+	inputs := [][]byte{
+		{},
+		[]byte("hello"),
+		bytes.Repeat([]byte("0123456789"), 1000),
+	}
+
+	for _, input := range inputs {
+		decoded, err := snappyDecode(snappy.Encode(nil, input))
+		if err != nil {
+			t.Fatalf("failed to decode %d bytes: %v", len(input), err)
+		}
+		if !bytes.Equal(decoded, input) {
+			t.Fatalf("expected %q, got %q", input, decoded)
+		}
+	}
+}
+
+func TestSnappyDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		src  []byte
+		err  error
+	}{
+		{name: "empty input", src: []byte{}},
+		{name: "truncated literal", src: []byte{5, 4 << 2, 'h', 'e'}},
+		{name: "copy offset of zero", src: []byte{5, 0, 'a', 0x01, 0}},
+		{name: "copy offset past output", src: []byte{5, 0, 'a', 0x01, 2}},
+		{name: "shorter than declared length", src: []byte{5, 0, 'a'}},
+		{name: "oversized length", src: binary.AppendUvarint(nil, 1<<31), err: errSnappyTooLarge},
+		{name: "length just over cap", src: binary.AppendUvarint(nil, snappyMaxDecodedLen+1), err: errSnappyTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := snappyDecode(test.src)
+			if !errors.Is(err, ErrDecodingFailure) {
+				t.Fatalf("expected ErrDecodingFailure, got %v", err)
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func FuzzSnappyDecode(f *testing.F) {
+	f.Add(snappy.Encode(nil, []byte("hello hello hello")))
+	f.Add([]byte{5, 0, 'a', 0x01, 2})
+	f.Add(binary.AppendUvarint(nil, 1<<32))
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		decoded, err := snappyDecode(src)
+		if err != nil {
+			return
+		}
+		if len(decoded) > snappyMaxDecodedLen {
+			t.Fatalf("decoded %d bytes, over the cap", len(decoded))
+		}
+	})
+}
@@ -0,0 +1,363 @@
+package gocbcoreps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/couchbase/goprotostellar/genproto/kv_v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LookupInSpecs builds the list of operations performed by a LookupIn. Modifiers,
+// such as Xattr, apply to the most recently added operation.
+type LookupInSpecs struct {
+	specs []*kv_v1.LookupInRequest_Spec
+}
+
+func NewLookupInSpecs() *LookupInSpecs {
+	return &LookupInSpecs{}
+}
+
+func (s *LookupInSpecs) add(op kv_v1.LookupInRequest_Spec_Operation, path string) *LookupInSpecs {
+	s.specs = append(s.specs, &kv_v1.LookupInRequest_Spec{
+		Operation: op,
+		Path:      path,
+	})
+	return s
+}
+
+// Get fetches the value at path, an empty path fetches the whole document.
+func (s *LookupInSpecs) Get(path string) *LookupInSpecs {
+	return s.add(kv_v1.LookupInRequest_Spec_OPERATION_GET, path)
+}
+
+// Exists checks whether path exists.
+func (s *LookupInSpecs) Exists(path string) *LookupInSpecs {
+	return s.add(kv_v1.LookupInRequest_Spec_OPERATION_EXISTS, path)
+}
+
+// Count returns the number of elements in the array or object at path.
+func (s *LookupInSpecs) Count(path string) *LookupInSpecs {
+	return s.add(kv_v1.LookupInRequest_Spec_OPERATION_COUNT, path)
+}
+
+// Xattr marks the previous operation as operating on extended attributes.
+func (s *LookupInSpecs) Xattr() *LookupInSpecs {
+	if len(s.specs) > 0 {
+		xattr := true
+		s.specs[len(s.specs)-1].Flags = &kv_v1.LookupInRequest_Spec_Flags{Xattr: &xattr}
+	}
+	return s
+}
+
+// MutateInSpecs builds the list of operations performed by a MutateIn. Modifiers,
+// such as Xattr and CreatePath, apply to the most recently added operation. Any
+// error encoding a value is returned by MutateIn.
+//
+// Values are always written literally, the server does not expand mutation macros
+// such as ${Mutation.CAS} as the protocol has no way to request it.
+type MutateInSpecs struct {
+	specs []*kv_v1.MutateInRequest_Spec
+	err   error
+}
+
+func NewMutateInSpecs() *MutateInSpecs {
+	return &MutateInSpecs{}
+}
+
+func (s *MutateInSpecs) add(op kv_v1.MutateInRequest_Spec_Operation, path string, content []byte) *MutateInSpecs {
+	s.specs = append(s.specs, &kv_v1.MutateInRequest_Spec{
+		Operation: op,
+		Path:      path,
+		Content:   content,
+	})
+	return s
+}
+
+func (s *MutateInSpecs) addValues(op kv_v1.MutateInRequest_Spec_Operation, path string, values ...interface{}) *MutateInSpecs {
+	// Multiple values are sent as a comma separated list of JSON values, without
+	// the surrounding array brackets.
+	encoded := make([][]byte, len(values))
+	for i, value := range values {
+		content, err := encodeJSONValue(value)
+		if err != nil {
+			if s.err == nil {
+				s.err = fmt.Errorf("encoding value for path '%s': %w", path, err)
+			}
+			return s.add(op, path, nil)
+		}
+
+		encoded[i] = content
+	}
+
+	return s.add(op, path, bytes.Join(encoded, []byte(",")))
+}
+
+func (s *MutateInSpecs) flags() *kv_v1.MutateInRequest_Spec_Flags {
+	spec := s.specs[len(s.specs)-1]
+	if spec.Flags == nil {
+		spec.Flags = &kv_v1.MutateInRequest_Spec_Flags{}
+	}
+
+	return spec.Flags
+}
+
+func (s *MutateInSpecs) Insert(path string, value interface{}) *MutateInSpecs {
+	return s.addValues(kv_v1.MutateInRequest_Spec_OPERATION_INSERT, path, value)
+}
+
+func (s *MutateInSpecs) Upsert(path string, value interface{}) *MutateInSpecs {
+	return s.addValues(kv_v1.MutateInRequest_Spec_OPERATION_UPSERT, path, value)
+}
+
+func (s *MutateInSpecs) Replace(path string, value interface{}) *MutateInSpecs {
+	return s.addValues(kv_v1.MutateInRequest_Spec_OPERATION_REPLACE, path, value)
+}
+
+func (s *MutateInSpecs) Remove(path string) *MutateInSpecs {
+	return s.add(kv_v1.MutateInRequest_Spec_OPERATION_REMOVE, path, nil)
+}
+
+func (s *MutateInSpecs) ArrayAppend(path string, values ...interface{}) *MutateInSpecs {
+	return s.addValues(kv_v1.MutateInRequest_Spec_OPERATION_ARRAY_APPEND, path, values...)
+}
+
+func (s *MutateInSpecs) ArrayPrepend(path string, values ...interface{}) *MutateInSpecs {
+	return s.addValues(kv_v1.MutateInRequest_Spec_OPERATION_ARRAY_PREPEND, path, values...)
+}
+
+func (s *MutateInSpecs) ArrayInsert(path string, values ...interface{}) *MutateInSpecs {
+	return s.addValues(kv_v1.MutateInRequest_Spec_OPERATION_ARRAY_INSERT, path, values...)
+}
+
+func (s *MutateInSpecs) ArrayAddUnique(path string, value interface{}) *MutateInSpecs {
+	return s.addValues(kv_v1.MutateInRequest_Spec_OPERATION_ARRAY_ADD_UNIQUE, path, value)
+}
+
+// Increment adds delta to the counter at path, the new value is available from the
+// result.
+func (s *MutateInSpecs) Increment(path string, delta int64) *MutateInSpecs {
+	return s.add(kv_v1.MutateInRequest_Spec_OPERATION_COUNTER, path, []byte(fmt.Sprintf("%d", delta)))
+}
+
+// Decrement subtracts delta from the counter at path, the new value is available
+// from the result.
+func (s *MutateInSpecs) Decrement(path string, delta int64) *MutateInSpecs {
+	return s.Increment(path, -delta)
+}
+
+// Xattr marks the previous operation as operating on extended attributes.
+func (s *MutateInSpecs) Xattr() *MutateInSpecs {
+	if len(s.specs) > 0 {
+		xattr := true
+		s.flags().Xattr = &xattr
+	}
+	return s
+}
+
+// CreatePath causes any missing parents of the previous operation's path to be created.
+func (s *MutateInSpecs) CreatePath() *MutateInSpecs {
+	if len(s.specs) > 0 {
+		createPath := true
+		s.flags().CreatePath = &createPath
+	}
+	return s
+}
+
+func encodeJSONValue(value interface{}) ([]byte, error) {
+	if raw, ok := value.(json.RawMessage); ok {
+		return raw, nil
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncodingFailure, err)
+	}
+
+	return content, nil
+}
+
+type LookupInOptions struct {
+	AccessDeleted bool
+}
+
+// LookupInResult contains the result of each LookupIn operation, indexed in the
+// order that they were specified.
+type LookupInResult struct {
+	Cas uint64
+
+	paths   []string
+	ops     []kv_v1.LookupInRequest_Spec_Operation
+	results []*kv_v1.LookupInResponse_Spec
+}
+
+// Err returns the error for the operation at idx, if it failed.
+func (r *LookupInResult) Err(idx int) error {
+	if idx < 0 || idx >= len(r.results) {
+		return fmt.Errorf("invalid lookup in index %d", idx)
+	}
+
+	st := r.results[idx].Status
+	if st == nil || codes.Code(st.Code) == codes.OK {
+		return nil
+	}
+
+	return fmt.Errorf("lookup in of path '%s' failed: %w", r.paths[idx], status.ErrorProto(st))
+}
+
+// Exists reports whether the path for the operation at idx exists.
+func (r *LookupInResult) Exists(idx int) bool {
+	if r.Err(idx) != nil {
+		return false
+	}
+
+	if r.ops[idx] == kv_v1.LookupInRequest_Spec_OPERATION_EXISTS {
+		var exists bool
+		if err := json.Unmarshal(r.results[idx].Content, &exists); err == nil {
+			return exists
+		}
+	}
+
+	return true
+}
+
+// ContentAt decodes the value of the operation at idx into valuePtr.
+func (r *LookupInResult) ContentAt(idx int, valuePtr interface{}) error {
+	if err := r.Err(idx); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(r.results[idx].Content, valuePtr); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+
+	return nil
+}
+
+// LookupInValue decodes the value of the operation at idx into a value of type T.
+func LookupInValue[T any](r *LookupInResult, idx int) (T, error) {
+	var value T
+	err := r.ContentAt(idx, &value)
+	return value, err
+}
+
+func (c *Collection) LookupIn(ctx context.Context, key string, specs *LookupInSpecs, opts *LookupInOptions) (*LookupInResult, error) {
+	if specs == nil || len(specs.specs) == 0 {
+		return nil, fmt.Errorf("%w: lookup in requires at least one spec", ErrInvalidArgument)
+	}
+
+	if opts == nil {
+		opts = &LookupInOptions{}
+	}
+
+	req := &kv_v1.LookupInRequest{
+		BucketName:     c.BucketName(),
+		ScopeName:      c.ScopeName(),
+		CollectionName: c.name,
+		Key:            key,
+		Specs:          specs.specs,
+	}
+	if opts.AccessDeleted {
+		req.Flags = &kv_v1.LookupInRequest_Flags{AccessDeleted: &opts.AccessDeleted}
+	}
+
+	resp, err := c.client().KvV1().LookupIn(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, len(specs.specs))
+	ops := make([]kv_v1.LookupInRequest_Spec_Operation, len(specs.specs))
+	for i, spec := range specs.specs {
+		paths[i] = spec.Path
+		ops[i] = spec.Operation
+	}
+
+	return &LookupInResult{
+		Cas:     resp.Cas,
+		paths:   paths,
+		ops:     ops,
+		results: resp.Specs,
+	}, nil
+}
+
+type MutateInOptions struct {
+	Cas             uint64
+	Expiry          time.Duration
+	StoreSemantic   *kv_v1.MutateInRequest_StoreSemantic
+	DurabilityLevel *kv_v1.DurabilityLevel
+	AccessDeleted   bool
+}
+
+// MutateInResult contains the result of a MutateIn, counter operations return
+// their new value which can be read with ContentAt.
+type MutateInResult struct {
+	Cas           uint64
+	MutationToken *kv_v1.MutationToken
+
+	results []*kv_v1.MutateInResponse_Spec
+}
+
+// ContentAt decodes the value returned by the operation at idx into valuePtr.
+func (r *MutateInResult) ContentAt(idx int, valuePtr interface{}) error {
+	if idx < 0 || idx >= len(r.results) {
+		return fmt.Errorf("invalid mutate in index %d", idx)
+	}
+
+	if err := json.Unmarshal(r.results[idx].Content, valuePtr); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+
+	return nil
+}
+
+func (c *Collection) MutateIn(ctx context.Context, key string, specs *MutateInSpecs, opts *MutateInOptions) (*MutateInResult, error) {
+	if specs == nil || len(specs.specs) == 0 {
+		return nil, fmt.Errorf("%w: mutate in requires at least one spec", ErrInvalidArgument)
+	}
+	if specs.err != nil {
+		return nil, specs.err
+	}
+
+	if opts == nil {
+		opts = &MutateInOptions{}
+	}
+
+	req := &kv_v1.MutateInRequest{
+		BucketName:      c.BucketName(),
+		ScopeName:       c.ScopeName(),
+		CollectionName:  c.name,
+		Key:             key,
+		Specs:           specs.specs,
+		StoreSemantic:   opts.StoreSemantic,
+		DurabilityLevel: opts.DurabilityLevel,
+	}
+	if opts.Cas > 0 {
+		req.Cas = &opts.Cas
+	}
+	if opts.Expiry > 0 {
+		if opts.Expiry > maxRelativeExpiry {
+			req.Expiry = &kv_v1.MutateInRequest_ExpiryTime{ExpiryTime: expiryTime(opts.Expiry)}
+		} else {
+			req.Expiry = &kv_v1.MutateInRequest_ExpirySecs{ExpirySecs: expirySecs(opts.Expiry)}
+		}
+	}
+	if opts.AccessDeleted {
+		req.Flags = &kv_v1.MutateInRequest_Flags{AccessDeleted: &opts.AccessDeleted}
+	}
+
+	resp, err := c.client().KvV1().MutateIn(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MutateInResult{
+		Cas:           resp.Cas,
+		MutationToken: resp.MutationToken,
+		results:       resp.Specs,
+	}, nil
+}
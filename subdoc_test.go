@@ -0,0 +1,70 @@
+package gocbcoreps
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/couchbase/goprotostellar/genproto/kv_v1"
+)
+
+func TestSubdocRejectsMissingSpecs(t *testing.T) {
+	coll := testCollection()
+
+	if _, err := coll.LookupIn(context.Background(), "key", nil, nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for nil lookup in specs, got %v", err)
+	}
+	if _, err := coll.LookupIn(context.Background(), "key", NewLookupInSpecs(), nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for empty lookup in specs, got %v", err)
+	}
+	if _, err := coll.MutateIn(context.Background(), "key", nil, nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for nil mutate in specs, got %v", err)
+	}
+	if _, err := coll.MutateIn(context.Background(), "key", NewMutateInSpecs(), nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for empty mutate in specs, got %v", err)
+	}
+}
+
+func TestMutateInSpecsEncoding(t *testing.T) {
+	specs := NewMutateInSpecs().
+		Upsert("a", map[string]int{"x": 1}).Xattr().CreatePath().
+		ArrayAppend("b", 1, "two").
+		Increment("c", 5).
+		Upsert("d", "${Mutation.CAS}").Xattr()
+
+	if len(specs.specs) != 4 {
+		t.Fatalf("expected 4 specs, got %d", len(specs.specs))
+	}
+
+	tests := []struct {
+		op         kv_v1.MutateInRequest_Spec_Operation
+		content    string
+		xattr      bool
+		createPath bool
+	}{
+		{kv_v1.MutateInRequest_Spec_OPERATION_UPSERT, `{"x":1}`, true, true},
+		{kv_v1.MutateInRequest_Spec_OPERATION_ARRAY_APPEND, `1,"two"`, false, false},
+		{kv_v1.MutateInRequest_Spec_OPERATION_COUNTER, `5`, false, false},
+		// Macros are not expanded, so they are written as a literal JSON string.
+		{kv_v1.MutateInRequest_Spec_OPERATION_UPSERT, `"${Mutation.CAS}"`, true, false},
+	}
+
+	for i, tt := range tests {
+		spec := specs.specs[i]
+		if spec.Operation != tt.op || string(spec.Content) != tt.content {
+			t.Fatalf("spec %d: expected %v %s, got %v %s", i, tt.op, tt.content, spec.Operation, spec.Content)
+		}
+		if spec.GetFlags().GetXattr() != tt.xattr || spec.GetFlags().GetCreatePath() != tt.createPath {
+			t.Fatalf("spec %d: unexpected flags %v", i, spec.GetFlags())
+		}
+	}
+}
+
+func TestMutateInSpecsEncodingFailure(t *testing.T) {
+	specs := NewMutateInSpecs().Upsert("a", make(chan int))
+
+	_, err := testCollection().MutateIn(context.Background(), "key", specs, nil)
+	if !errors.Is(err, ErrEncodingFailure) {
+		t.Fatalf("expected ErrEncodingFailure, got %v", err)
+	}
+}
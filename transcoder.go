@@ -0,0 +1,212 @@
+package gocbcoreps
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Common flags, which specify the format of a document, shared by all Couchbase SDKs.
+const (
+	commonFlagsFormatMask    uint32 = 0xFF000000
+	commonFlagsFormatPrivate uint32 = 1 << 24
+	commonFlagsFormatJSON    uint32 = 2 << 24
+	commonFlagsFormatBinary  uint32 = 3 << 24
+	commonFlagsFormatString  uint32 = 4 << 24
+)
+
+type documentFormat uint8
+
+const (
+	formatUnknown documentFormat = iota
+	formatJSON
+	formatBinary
+	formatString
+)
+
+func decodeCommonFlags(flags uint32) documentFormat {
+	switch flags & commonFlagsFormatMask {
+	case commonFlagsFormatJSON:
+		return formatJSON
+	case commonFlagsFormatBinary, commonFlagsFormatPrivate:
+		return formatBinary
+	case commonFlagsFormatString:
+		return formatString
+	}
+
+	if flags == 0 {
+		// Documents written by legacy clients without any flags are JSON.
+		return formatJSON
+	}
+
+	return formatUnknown
+}
+
+// Transcoder encodes values into documents, and their content flags, and decodes
+// documents back into values.
+type Transcoder interface {
+	Decode(content []byte, flags uint32, valuePtr interface{}) error
+	Encode(value interface{}) ([]byte, uint32, error)
+}
+
+// JSONTranscoder encodes and decodes values as JSON documents.
+type JSONTranscoder struct{}
+
+var _ Transcoder = (*JSONTranscoder)(nil)
+
+func NewJSONTranscoder() *JSONTranscoder {
+	return &JSONTranscoder{}
+}
+
+func (t *JSONTranscoder) Decode(content []byte, flags uint32, valuePtr interface{}) error {
+	if decodeCommonFlags(flags) != formatJSON {
+		return fmt.Errorf("%w: json transcoder cannot decode non-json document", ErrDecodingFailure)
+	}
+
+	switch v := valuePtr.(type) {
+	case *json.RawMessage:
+		*v = append((*v)[:0], content...)
+		return nil
+	case *[]byte:
+		*v = append((*v)[:0], content...)
+		return nil
+	}
+
+	if err := json.Unmarshal(content, valuePtr); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+
+	return nil
+}
+
+func (t *JSONTranscoder) Encode(value interface{}) ([]byte, uint32, error) {
+	switch v := value.(type) {
+	case json.RawMessage:
+		return v, commonFlagsFormatJSON, nil
+	case []byte:
+		return nil, 0, fmt.Errorf("%w: json transcoder cannot encode binary values, use json.RawMessage for pre-encoded json", ErrEncodingFailure)
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrEncodingFailure, err)
+	}
+
+	return content, commonFlagsFormatJSON, nil
+}
+
+// RawBinaryTranscoder passes []byte values through untouched, as binary documents.
+type RawBinaryTranscoder struct{}
+
+var _ Transcoder = (*RawBinaryTranscoder)(nil)
+
+func NewRawBinaryTranscoder() *RawBinaryTranscoder {
+	return &RawBinaryTranscoder{}
+}
+
+func (t *RawBinaryTranscoder) Decode(content []byte, flags uint32, valuePtr interface{}) error {
+	if decodeCommonFlags(flags) != formatBinary {
+		return fmt.Errorf("%w: raw binary transcoder cannot decode non-binary document", ErrDecodingFailure)
+	}
+
+	v, ok := valuePtr.(*[]byte)
+	if !ok {
+		return fmt.Errorf("%w: raw binary transcoder can only decode into *[]byte", ErrDecodingFailure)
+	}
+
+	*v = append((*v)[:0], content...)
+	return nil
+}
+
+func (t *RawBinaryTranscoder) Encode(value interface{}) ([]byte, uint32, error) {
+	v, ok := value.([]byte)
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: raw binary transcoder can only encode []byte", ErrEncodingFailure)
+	}
+
+	return v, commonFlagsFormatBinary, nil
+}
+
+// RawStringTranscoder passes string values through untouched, as string documents.
+type RawStringTranscoder struct{}
+
+var _ Transcoder = (*RawStringTranscoder)(nil)
+
+func NewRawStringTranscoder() *RawStringTranscoder {
+	return &RawStringTranscoder{}
+}
+
+func (t *RawStringTranscoder) Decode(content []byte, flags uint32, valuePtr interface{}) error {
+	if decodeCommonFlags(flags) != formatString {
+		return fmt.Errorf("%w: raw string transcoder cannot decode non-string document", ErrDecodingFailure)
+	}
+
+	v, ok := valuePtr.(*string)
+	if !ok {
+		return fmt.Errorf("%w: raw string transcoder can only decode into *string", ErrDecodingFailure)
+	}
+
+	*v = string(content)
+	return nil
+}
+
+func (t *RawStringTranscoder) Encode(value interface{}) ([]byte, uint32, error) {
+	v, ok := value.(string)
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: raw string transcoder can only encode string", ErrEncodingFailure)
+	}
+
+	return []byte(v), commonFlagsFormatString, nil
+}
+
+// LegacyTranscoder is compatible with documents written by older SDKs. []byte values
+// are stored as binary, strings as strings and everything else as JSON. Decoding
+// is driven by the document flags.
+type LegacyTranscoder struct{}
+
+var _ Transcoder = (*LegacyTranscoder)(nil)
+
+func NewLegacyTranscoder() *LegacyTranscoder {
+	return &LegacyTranscoder{}
+}
+
+func (t *LegacyTranscoder) Decode(content []byte, flags uint32, valuePtr interface{}) error {
+	switch decodeCommonFlags(flags) {
+	case formatBinary:
+		switch v := valuePtr.(type) {
+		case *[]byte:
+			*v = append((*v)[:0], content...)
+			return nil
+		case *interface{}:
+			*v = append([]byte(nil), content...)
+			return nil
+		}
+
+		return fmt.Errorf("%w: binary documents can only be decoded into *[]byte or *interface{}", ErrDecodingFailure)
+	case formatString:
+		switch v := valuePtr.(type) {
+		case *string:
+			*v = string(content)
+			return nil
+		case *interface{}:
+			*v = string(content)
+			return nil
+		}
+
+		return fmt.Errorf("%w: string documents can only be decoded into *string or *interface{}", ErrDecodingFailure)
+	case formatJSON:
+		return NewJSONTranscoder().Decode(content, flags, valuePtr)
+	}
+
+	return fmt.Errorf("%w: unknown document flags %#x", ErrDecodingFailure, flags)
+}
+
+func (t *LegacyTranscoder) Encode(value interface{}) ([]byte, uint32, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, commonFlagsFormatBinary, nil
+	case string:
+		return []byte(v), commonFlagsFormatString, nil
+	}
+
+	return NewJSONTranscoder().Encode(value)
+}
@@ -0,0 +1,176 @@
+package gocbcoreps
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCommonFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags uint32
+		want  documentFormat
+	}{
+		{"legacy", 0, formatJSON},
+		{"json", commonFlagsFormatJSON, formatJSON},
+		{"binary", commonFlagsFormatBinary, formatBinary},
+		{"private", commonFlagsFormatPrivate, formatBinary},
+		{"string", commonFlagsFormatString, formatString},
+		{"json with compression bits", commonFlagsFormatJSON | 0x1, formatJSON},
+		{"unknown format", 5 << 24, formatUnknown},
+		{"legacy flags only", 0x1, formatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeCommonFlags(tt.flags); got != tt.want {
+				t.Fatalf("expected format %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+type transcoderTestDoc struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestTranscoderEncodeFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		transcoder  Transcoder
+		value       interface{}
+		wantContent []byte
+		wantFlags   uint32
+		wantErr     error
+	}{
+		{"json struct", NewJSONTranscoder(), transcoderTestDoc{Name: "a", Age: 1}, []byte(`{"name":"a","age":1}`), commonFlagsFormatJSON, nil},
+		{"json raw", NewJSONTranscoder(), json.RawMessage(`[1,2]`), []byte(`[1,2]`), commonFlagsFormatJSON, nil},
+		{"json string", NewJSONTranscoder(), "hi", []byte(`"hi"`), commonFlagsFormatJSON, nil},
+		{"json bytes", NewJSONTranscoder(), []byte("hi"), nil, 0, ErrEncodingFailure},
+		{"json unencodable", NewJSONTranscoder(), make(chan int), nil, 0, ErrEncodingFailure},
+		{"binary bytes", NewRawBinaryTranscoder(), []byte{0x00, 0xff}, []byte{0x00, 0xff}, commonFlagsFormatBinary, nil},
+		{"binary string", NewRawBinaryTranscoder(), "hi", nil, 0, ErrEncodingFailure},
+		{"string string", NewRawStringTranscoder(), "hi", []byte("hi"), commonFlagsFormatString, nil},
+		{"string bytes", NewRawStringTranscoder(), []byte("hi"), nil, 0, ErrEncodingFailure},
+		{"legacy bytes", NewLegacyTranscoder(), []byte{0x01}, []byte{0x01}, commonFlagsFormatBinary, nil},
+		{"legacy string", NewLegacyTranscoder(), "hi", []byte("hi"), commonFlagsFormatString, nil},
+		{"legacy struct", NewLegacyTranscoder(), transcoderTestDoc{Name: "a"}, []byte(`{"name":"a","age":0}`), commonFlagsFormatJSON, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, flags, err := tt.transcoder.Encode(tt.value)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if flags != tt.wantFlags {
+				t.Fatalf("expected flags %#x, got %#x", tt.wantFlags, flags)
+			}
+			if string(content) != string(tt.wantContent) {
+				t.Fatalf("expected content %q, got %q", tt.wantContent, content)
+			}
+		})
+	}
+}
+
+func TestTranscoderRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		transcoder Transcoder
+		value      interface{}
+		// newPtr returns a pointer to decode the encoded value into.
+		newPtr func() interface{}
+	}{
+		{"json struct", NewJSONTranscoder(), transcoderTestDoc{Name: "a", Age: 1}, func() interface{} { return &transcoderTestDoc{} }},
+		{"json map", NewJSONTranscoder(), map[string]interface{}{"a": "b"}, func() interface{} { return &map[string]interface{}{} }},
+		{"json raw", NewJSONTranscoder(), json.RawMessage(`{"a":1}`), func() interface{} { return &json.RawMessage{} }},
+		{"binary", NewRawBinaryTranscoder(), []byte{0x00, 0x01, 0xff}, func() interface{} { return &[]byte{} }},
+		{"string", NewRawStringTranscoder(), "hello", func() interface{} { var s string; return &s }},
+		{"legacy bytes", NewLegacyTranscoder(), []byte{0x02}, func() interface{} { return &[]byte{} }},
+		{"legacy string", NewLegacyTranscoder(), "hello", func() interface{} { var s string; return &s }},
+		{"legacy struct", NewLegacyTranscoder(), transcoderTestDoc{Name: "b", Age: 2}, func() interface{} { return &transcoderTestDoc{} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, flags, err := tt.transcoder.Encode(tt.value)
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+
+			ptr := tt.newPtr()
+			if err := tt.transcoder.Decode(content, flags, ptr); err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+
+			if got := reflect.ValueOf(ptr).Elem().Interface(); !reflect.DeepEqual(got, tt.value) {
+				t.Fatalf("expected %#v, got %#v", tt.value, got)
+			}
+		})
+	}
+}
+
+func TestTranscoderDecodeRejectsMismatchedFlags(t *testing.T) {
+	var b []byte
+	var s string
+	var v interface{}
+
+	tests := []struct {
+		name       string
+		transcoder Transcoder
+		flags      uint32
+		valuePtr   interface{}
+	}{
+		{"json binary doc", NewJSONTranscoder(), commonFlagsFormatBinary, &v},
+		{"json string doc", NewJSONTranscoder(), commonFlagsFormatString, &v},
+		{"binary json doc", NewRawBinaryTranscoder(), commonFlagsFormatJSON, &b},
+		{"binary wrong ptr", NewRawBinaryTranscoder(), commonFlagsFormatBinary, &s},
+		{"string json doc", NewRawStringTranscoder(), commonFlagsFormatJSON, &s},
+		{"string wrong ptr", NewRawStringTranscoder(), commonFlagsFormatString, &b},
+		{"legacy unknown flags", NewLegacyTranscoder(), 5 << 24, &v},
+		{"legacy binary wrong ptr", NewLegacyTranscoder(), commonFlagsFormatBinary, &s},
+		{"legacy string wrong ptr", NewLegacyTranscoder(), commonFlagsFormatString, &b},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.transcoder.Decode([]byte("x"), tt.flags, tt.valuePtr)
+			if !errors.Is(err, ErrDecodingFailure) {
+				t.Fatalf("expected ErrDecodingFailure, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLegacyTranscoderDecodesIntoInterface(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags uint32
+		want  interface{}
+	}{
+		{"binary", commonFlagsFormatBinary, []byte(`"x"`)},
+		{"string", commonFlagsFormatString, `"x"`},
+		{"json", commonFlagsFormatJSON, "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			if err := NewLegacyTranscoder().Decode([]byte(`"x"`), tt.flags, &v); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(v, tt.want) {
+				t.Fatalf("expected %#v, got %#v", tt.want, v)
+			}
+		})
+	}
+}
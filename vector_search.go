@@ -0,0 +1,167 @@
+package gocbcoreps
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/couchbase/goprotostellar/genproto/search_v1"
+)
+
+// NewVectorQueryBase64 creates a query from a base64 encoded vector of little
+// endian float32s, as produced by EncodeVectorBase64. Any decoding error is
+// returned when the search is performed.
+func NewVectorQueryBase64(field string, encoded string) *VectorQuery {
+	vector, err := DecodeVectorBase64(encoded)
+
+	q := NewVectorQuery(field, vector)
+	q.err = err
+	return q
+}
+
+// EncodeVectorBase64 packs a vector as little endian float32s and base64 encodes it.
+func EncodeVectorBase64(vector []float32) string {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// DecodeVectorBase64 reverses EncodeVectorBase64.
+func DecodeVectorBase64(encoded string) ([]float32, error) {
+	buf, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidVector, err)
+	}
+	if len(buf)%4 != 0 {
+		return nil, fmt.Errorf("%w: encoded length %d is not a multiple of 4", ErrInvalidVector, len(buf))
+	}
+
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+
+	return vector, nil
+}
+
+type VectorSearchOptions struct {
+	// Query is a full-text query which is ORed with the vector queries, hits matching
+	// either the query or the vector queries are returned and scored together. By
+	// default only vector hits are returned.
+	Query SearchQuery
+
+	// PreFilter restricts vector hits to documents matching a full-text query, ANDing
+	// it with the vector queries. The search protocol cannot carry a pre-filter, so
+	// setting it returns ErrFeatureNotAvailable.
+	PreFilter SearchQuery
+
+	// Limit is the maximum number of hits returned. Without a Query this defaults to
+	// the sum of k across all vector queries, otherwise the server default is used so
+	// that full-text hits are not truncated.
+	Limit       uint32
+	Fields      []string
+	Collections []string
+}
+
+// VectorSearchResult contains the hits of a vector search, ordered by score.
+type VectorSearchResult struct {
+	Hits     []*SearchRow
+	MetaData *SearchMetaData
+}
+
+// Scores returns the score of each hit, keyed by document id.
+func (r *VectorSearchResult) Scores() map[string]float64 {
+	scores := make(map[string]float64, len(r.Hits))
+	for _, hit := range r.Hits {
+		scores[hit.ID] = hit.Score
+	}
+	return scores
+}
+
+func (s *VectorSearch) toRequest(indexName string, opts *VectorSearchOptions) (*search_v1.SearchQueryRequest, error) {
+	if opts == nil {
+		opts = &VectorSearchOptions{}
+	}
+	if opts.PreFilter != nil {
+		return nil, fmt.Errorf("%w: vector search pre-filters are not supported by the search protocol", ErrFeatureNotAvailable)
+	}
+
+	req := &search_v1.SearchQueryRequest{
+		IndexName:   indexName,
+		Limit:       opts.Limit,
+		Fields:      opts.Fields,
+		Collections: opts.Collections,
+	}
+
+	// Without a query the full-text part of the request must match nothing, so that
+	// only vector hits are returned.
+	if opts.Query != nil {
+		req.Query = opts.Query.toProto()
+	} else {
+		req.Query = NewMatchNoneQuery().toProto()
+		if req.Limit == 0 {
+			req.Limit = uint32(s.totalK())
+		}
+	}
+
+	if err := s.apply(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func executeVectorSearch(ctx context.Context, client *RoutingClient, req *search_v1.SearchQueryRequest) (*VectorSearchResult, error) {
+	result, err := executeSearch(ctx, client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []*SearchRow
+	for hit, err := range result.Rows() {
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+
+	meta, err := result.MetaData()
+	if err != nil {
+		return nil, err
+	}
+
+	return &VectorSearchResult{
+		Hits:     hits,
+		MetaData: meta,
+	}, nil
+}
+
+// VectorSearch executes a vector search against a cluster level index.
+func (c *Cluster) VectorSearch(ctx context.Context, indexName string, search *VectorSearch, opts *VectorSearchOptions) (*VectorSearchResult, error) {
+	req, err := search.toRequest(indexName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return executeVectorSearch(ctx, c.client, req)
+}
+
+// VectorSearch executes a vector search against an index belonging to this scope.
+func (s *Scope) VectorSearch(ctx context.Context, indexName string, search *VectorSearch, opts *VectorSearchOptions) (*VectorSearchResult, error) {
+	req, err := search.toRequest(indexName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName := s.BucketName()
+	scopeName := s.name
+	req.BucketName = &bucketName
+	req.ScopeName = &scopeName
+
+	return executeVectorSearch(ctx, s.bucket.cluster.client, req)
+}
@@ -0,0 +1,151 @@
+package gocbcoreps
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEncodeVectorBase64(t *testing.T) {
+	// 1.0 is 0x3f800000 and -2.0 is 0xc0000000, packed little endian.
+	encoded := EncodeVectorBase64([]float32{1, -2})
+	expected := base64.StdEncoding.EncodeToString([]byte{0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x00, 0xc0})
+	if encoded != expected {
+		t.Fatalf("expected %q, got %q", expected, encoded)
+	}
+
+	if encoded := EncodeVectorBase64(nil); encoded != "" {
+		t.Fatalf("expected an empty vector to encode as empty, got %q", encoded)
+	}
+}
+
+func TestDecodeVectorBase64(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoded  string
+		expected []float32
+		err      error
+	}{
+		{
+			name:     "little endian",
+			encoded:  base64.StdEncoding.EncodeToString([]byte{0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x00, 0xc0}),
+			expected: []float32{1, -2},
+		},
+		{
+			name:     "round trip",
+			encoded:  EncodeVectorBase64([]float32{0.25, math.MaxFloat32, -0.5}),
+			expected: []float32{0.25, math.MaxFloat32, -0.5},
+		},
+		{
+			name:    "length not a multiple of four",
+			encoded: base64.StdEncoding.EncodeToString([]byte{1, 2, 3, 4, 5, 6}),
+			err:     ErrInvalidVector,
+		},
+		{
+			name:    "bad base64",
+			encoded: "not base64!",
+			err:     ErrInvalidVector,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vector, err := DecodeVectorBase64(test.encoded)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if test.err == nil && !reflect.DeepEqual(vector, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, vector)
+			}
+		})
+	}
+}
+
+func TestVectorQueryValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *VectorQuery
+		err   error
+	}{
+		{name: "valid", query: NewVectorQuery("vec", []float32{1, 2})},
+		{name: "valid base64", query: NewVectorQueryBase64("vec", EncodeVectorBase64([]float32{1}))},
+		{name: "no vector", query: NewVectorQuery("vec", nil), err: ErrInvalidVector},
+		{name: "zero k", query: NewVectorQuery("vec", []float32{1}).NumCandidates(0), err: ErrInvalidVector},
+		{name: "negative k", query: NewVectorQuery("vec", []float32{1}).NumCandidates(-1), err: ErrInvalidVector},
+		{name: "nan", query: NewVectorQuery("vec", []float32{1, float32(math.NaN())}), err: ErrInvalidVector},
+		{name: "infinity", query: NewVectorQuery("vec", []float32{float32(math.Inf(-1))}), err: ErrInvalidVector},
+		{name: "bad base64", query: NewVectorQueryBase64("vec", "!!"), err: ErrInvalidVector},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.query.validate(); !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestVectorSearchToRequest(t *testing.T) {
+	search := NewVectorSearch(
+		NewVectorQuery("vec", []float32{1}).NumCandidates(4),
+		NewVectorQuery("vec", []float32{2}).NumCandidates(6),
+	)
+
+	req, err := search.toRequest("idx", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !proto.Equal(req.Query, NewMatchNoneQuery().toProto()) {
+		t.Fatalf("expected only vector hits without a query, got %v", req.Query)
+	}
+	if req.Limit != 10 {
+		t.Fatalf("expected the limit to default to the sum of k, got %d", req.Limit)
+	}
+	if req.KnnOperator != nil {
+		t.Fatalf("expected no knn operator by default, got %v", req.GetKnnOperator())
+	}
+
+	text := NewTermQuery("hotel").Field("type")
+	req, err = search.toRequest("idx", &VectorSearchOptions{Query: text})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !proto.Equal(req.Query, text.toProto()) {
+		t.Fatalf("expected the query to be sent to the server, got %v", req.Query)
+	}
+	if req.Limit != 0 {
+		t.Fatalf("expected the server default limit with a query, got %d", req.Limit)
+	}
+
+	req, err = search.toRequest("idx", &VectorSearchOptions{Query: text, Limit: 25})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Limit != 25 {
+		t.Fatalf("expected an explicit limit to be kept, got %d", req.Limit)
+	}
+
+	_, err = search.toRequest("idx", &VectorSearchOptions{PreFilter: text})
+	if !errors.Is(err, ErrFeatureNotAvailable) {
+		t.Fatalf("expected ErrFeatureNotAvailable for a pre-filter, got %v", err)
+	}
+
+	_, err = NewVectorSearch(NewVectorQueryBase64("vec", "!!")).toRequest("idx", nil)
+	if !errors.Is(err, ErrInvalidVector) {
+		t.Fatalf("expected ErrInvalidVector, got %v", err)
+	}
+}
+
+func TestVectorSearchResultScores(t *testing.T) {
+	result := &VectorSearchResult{Hits: []*SearchRow{{ID: "a", Score: 0.5}, {ID: "b", Score: 0.25}}}
+
+	expected := map[string]float64{"a": 0.5, "b": 0.25}
+	if scores := result.Scores(); !reflect.DeepEqual(scores, expected) {
+		t.Fatalf("expected %v, got %v", expected, scores)
+	}
+}
@@ -0,0 +1,274 @@
+package gocbcoreps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/couchbase/goprotostellar/genproto/view_v1"
+)
+
+const devDesignDocPrefix = "dev_"
+
+type ViewOptions struct {
+	// ScanConsistency replaces the legacy stale parameter, NOT_BOUNDED is stale=ok,
+	// REQUEST_PLUS is stale=false and UPDATE_AFTER is stale=update_after.
+	ScanConsistency *view_v1.ViewQueryRequest_ScanConsistency
+
+	// Namespace selects the production or development version of the design
+	// document, by default production unless the name has a dev_ prefix.
+	Namespace *view_v1.ViewQueryRequest_Namespace
+
+	Skip  uint32
+	Limit uint32
+	Order *view_v1.ViewQueryRequest_Order
+
+	// Reduce enables or disables the view's reduce function, by default the view's
+	// reduce function is used if it has one.
+	Reduce     *bool
+	Group      bool
+	GroupLevel uint32
+
+	// Key, Keys, StartKey and EndKey are JSON encoded.
+	Key      any
+	Keys     []any
+	StartKey any
+	EndKey   any
+
+	// InclusiveEnd controls whether rows matching EndKey are included, by default
+	// the server includes them.
+	InclusiveEnd  *bool
+	StartKeyDocID string
+	EndKeyDocID   string
+
+	OnError *view_v1.ViewQueryRequest_ErrorMode
+	Debug   bool
+}
+
+func (o *ViewOptions) toRequest(bucketName, designDocName, viewName string) (*view_v1.ViewQueryRequest, error) {
+	// Design documents are often referred to by their development name, in which
+	// case the namespace is implied unless explicitly specified.
+	namespace := o.Namespace
+	if name, ok := strings.CutPrefix(designDocName, devDesignDocPrefix); ok {
+		designDocName = name
+		if namespace == nil {
+			namespace = view_v1.ViewQueryRequest_NAMESPACE_DEVELOPMENT.Enum()
+		}
+	}
+
+	req := &view_v1.ViewQueryRequest{
+		BucketName:         bucketName,
+		DesignDocumentName: designDocName,
+		ViewName:           viewName,
+		ScanConsistency:    o.ScanConsistency,
+		Namespace:          namespace,
+		Order:              o.Order,
+		Reduce:             o.Reduce,
+		InclusiveEnd:       o.InclusiveEnd,
+		OnError:            o.OnError,
+	}
+
+	if o.Skip > 0 {
+		req.Skip = &o.Skip
+	}
+	if o.Limit > 0 {
+		req.Limit = &o.Limit
+	}
+	if o.Group {
+		req.Group = &o.Group
+	}
+	if o.GroupLevel > 0 {
+		req.GroupLevel = &o.GroupLevel
+	}
+	if o.StartKeyDocID != "" {
+		req.StartKeyDocId = &o.StartKeyDocID
+	}
+	if o.EndKeyDocID != "" {
+		req.EndKeyDocId = &o.EndKeyDocID
+	}
+	if o.Debug {
+		req.Debug = &o.Debug
+	}
+
+	var err error
+	if req.Key, err = encodeViewKey("key", o.Key); err != nil {
+		return nil, err
+	}
+	if req.StartKey, err = encodeViewKey("start key", o.StartKey); err != nil {
+		return nil, err
+	}
+	if req.EndKey, err = encodeViewKey("end key", o.EndKey); err != nil {
+		return nil, err
+	}
+	for _, key := range o.Keys {
+		encoded, err := encodeViewKey("keys", key)
+		if err != nil {
+			return nil, err
+		}
+		req.Keys = append(req.Keys, encoded)
+	}
+
+	return req, nil
+}
+
+func encodeViewKey(name string, key any) ([]byte, error) {
+	if key == nil {
+		return nil, nil
+	}
+
+	encoded, err := encodeJSONValue(key)
+	if err != nil {
+		return nil, fmt.Errorf("encoding view %s: %w", name, err)
+	}
+
+	return encoded, nil
+}
+
+type ViewRow struct {
+	ID string
+
+	key   []byte
+	value []byte
+}
+
+// Key decodes the key emitted for this row into valuePtr.
+func (r *ViewRow) Key(valuePtr interface{}) error {
+	if err := json.Unmarshal(r.key, valuePtr); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+	return nil
+}
+
+// Value decodes the value emitted for this row into valuePtr.
+func (r *ViewRow) Value(valuePtr interface{}) error {
+	if err := json.Unmarshal(r.value, valuePtr); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+	return nil
+}
+
+type ViewMetaData struct {
+	TotalRows uint64
+	Debug     json.RawMessage
+}
+
+// ViewResult iterates over the rows of a view query, rows must be fully read before
+// its metadata becomes available.
+type ViewResult struct {
+	rowStream[*ViewRow]
+
+	meta *view_v1.ViewQueryResponse_MetaData
+}
+
+// NewViewResult wraps a stream returned by ViewV1().ViewQuery. Closing the result
+// before all rows are read drains the stream, cancel the context used to create the
+// stream to abandon it instead.
+func NewViewResult(stream view_v1.ViewService_ViewQueryClient) *ViewResult {
+	return newViewResult(stream, nil)
+}
+
+func newViewResult(stream view_v1.ViewService_ViewQueryClient, cancel context.CancelFunc) *ViewResult {
+	r := &ViewResult{}
+	r.cancel = cancel
+	r.recv = func() ([]*ViewRow, error) {
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.MetaData != nil {
+			r.meta = resp.MetaData
+		}
+
+		rows := make([]*ViewRow, len(resp.Rows))
+		for i, row := range resp.Rows {
+			rows[i] = &ViewRow{
+				ID:    row.Id,
+				key:   row.Key,
+				value: row.Value,
+			}
+		}
+
+		return rows, nil
+	}
+
+	return r
+}
+
+// Next advances to the next row, returning false once there are no more rows or an
+// error occurred, in which case Err returns the error.
+func (r *ViewResult) Next() bool {
+	return r.next()
+}
+
+// Row returns the current row.
+func (r *ViewResult) Row() *ViewRow {
+	return r.row
+}
+
+// Err returns any error which occurred during iteration.
+func (r *ViewResult) Err() error {
+	return r.err
+}
+
+// Close stops iteration and releases the underlying stream.
+func (r *ViewResult) Close() error {
+	return r.close()
+}
+
+// Rows returns an iterator over the rows of the result. The result is closed when
+// iteration stops, any stream error is yielded last.
+func (r *ViewResult) Rows() iter.Seq2[*ViewRow, error] {
+	return func(yield func(*ViewRow, error) bool) {
+		defer r.Close()
+
+		for r.Next() {
+			if !yield(r.Row(), nil) {
+				return
+			}
+		}
+
+		if err := r.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// MetaData returns the metadata of the view query, which is only available once all
+// rows have been read.
+func (r *ViewResult) MetaData() (*ViewMetaData, error) {
+	if !r.finished {
+		return nil, ErrResultNotFinished
+	}
+	if r.meta == nil {
+		return nil, ErrNoResult
+	}
+
+	return &ViewMetaData{
+		TotalRows: r.meta.TotalRows,
+		Debug:     r.meta.Debug,
+	}, nil
+}
+
+// ViewQuery executes a query against a view in one of this bucket's design documents.
+func (b *Bucket) ViewQuery(ctx context.Context, designDocName, viewName string, opts *ViewOptions) (*ViewResult, error) {
+	if opts == nil {
+		opts = &ViewOptions{}
+	}
+
+	req, err := opts.toRequest(b.name, designDocName, viewName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := b.cluster.client.ViewV1().ViewQuery(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return newViewResult(stream, cancel), nil
+}
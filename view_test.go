@@ -0,0 +1,185 @@
+package gocbcoreps
+
+import (
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/couchbase/goprotostellar/genproto/view_v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeViewStream struct {
+	grpc.ClientStream
+
+	resps []*view_v1.ViewQueryResponse
+	err   error
+}
+
+func (s *fakeViewStream) Recv() (*view_v1.ViewQueryResponse, error) {
+	if len(s.resps) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+
+	resp := s.resps[0]
+	s.resps = s.resps[1:]
+	return resp, nil
+}
+
+func TestViewOptionsToRequest(t *testing.T) {
+	development := view_v1.ViewQueryRequest_NAMESPACE_DEVELOPMENT
+	production := view_v1.ViewQueryRequest_NAMESPACE_PRODUCTION
+
+	tests := []struct {
+		name          string
+		designDocName string
+		opts          ViewOptions
+		expected      *view_v1.ViewQueryRequest
+	}{
+		{
+			name:          "defaults",
+			designDocName: "ddoc",
+			expected: &view_v1.ViewQueryRequest{
+				BucketName:         "bucket",
+				DesignDocumentName: "ddoc",
+				ViewName:           "view",
+			},
+		},
+		{
+			name:          "dev prefix implies development namespace",
+			designDocName: "dev_ddoc",
+			expected: &view_v1.ViewQueryRequest{
+				BucketName:         "bucket",
+				DesignDocumentName: "ddoc",
+				ViewName:           "view",
+				Namespace:          &development,
+			},
+		},
+		{
+			name:          "explicit namespace wins over dev prefix",
+			designDocName: "dev_ddoc",
+			opts:          ViewOptions{Namespace: &production},
+			expected: &view_v1.ViewQueryRequest{
+				BucketName:         "bucket",
+				DesignDocumentName: "ddoc",
+				ViewName:           "view",
+				Namespace:          &production,
+			},
+		},
+		{
+			name:          "inclusive end false is sent",
+			designDocName: "ddoc",
+			opts:          ViewOptions{InclusiveEnd: proto.Bool(false)},
+			expected: &view_v1.ViewQueryRequest{
+				BucketName:         "bucket",
+				DesignDocumentName: "ddoc",
+				ViewName:           "view",
+				InclusiveEnd:       proto.Bool(false),
+			},
+		},
+		{
+			name:          "keys are json encoded",
+			designDocName: "ddoc",
+			opts: ViewOptions{
+				Key:      "a",
+				Keys:     []any{1, []string{"b"}},
+				StartKey: []int{1},
+				EndKey:   map[string]int{"c": 2},
+				Limit:    10,
+				Skip:     2,
+				Group:    true,
+			},
+			expected: &view_v1.ViewQueryRequest{
+				BucketName:         "bucket",
+				DesignDocumentName: "ddoc",
+				ViewName:           "view",
+				Key:                []byte(`"a"`),
+				Keys:               [][]byte{[]byte(`1`), []byte(`["b"]`)},
+				StartKey:           []byte(`[1]`),
+				EndKey:             []byte(`{"c":2}`),
+				Limit:              proto.Uint32(10),
+				Skip:               proto.Uint32(2),
+				Group:              proto.Bool(true),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := test.opts.toRequest("bucket", test.designDocName, "view")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !proto.Equal(req, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, req)
+			}
+		})
+	}
+
+	_, err := (&ViewOptions{EndKey: math.NaN()}).toRequest("bucket", "ddoc", "view")
+	if !errors.Is(err, ErrEncodingFailure) {
+		t.Fatalf("expected ErrEncodingFailure, got %v", err)
+	}
+}
+
+func TestViewResult(t *testing.T) {
+	r := NewViewResult(&fakeViewStream{resps: []*view_v1.ViewQueryResponse{
+		{Rows: []*view_v1.ViewQueryResponse_Row{
+			{Id: "a", Key: []byte(`"ka"`), Value: []byte(`1`)},
+			{Id: "b", Key: []byte(`"kb"`), Value: []byte(`2`)},
+		}},
+		{MetaData: &view_v1.ViewQueryResponse_MetaData{TotalRows: 2}},
+	}})
+
+	if _, err := r.MetaData(); !errors.Is(err, ErrResultNotFinished) {
+		t.Fatalf("expected ErrResultNotFinished, got %v", err)
+	}
+
+	var total int
+	for row, err := range r.Rows() {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var key string
+		var value int
+		if err := row.Key(&key); err != nil || key != "k"+row.ID {
+			t.Fatalf("unexpected key %q, %v", key, err)
+		}
+		if err := row.Value(&value); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		total += value
+	}
+	if total != 3 {
+		t.Fatalf("expected values to sum to 3, got %d", total)
+	}
+
+	meta, err := r.MetaData()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.TotalRows != 2 {
+		t.Fatalf("expected total rows 2, got %d", meta.TotalRows)
+	}
+}
+
+func TestViewResultStreamError(t *testing.T) {
+	streamErr := errors.New("stream failed")
+	r := NewViewResult(&fakeViewStream{err: streamErr})
+
+	if r.Next() {
+		t.Fatalf("expected no rows")
+	}
+	if !errors.Is(r.Err(), streamErr) {
+		t.Fatalf("expected stream error, got %v", r.Err())
+	}
+	if _, err := r.MetaData(); !errors.Is(err, ErrNoResult) {
+		t.Fatalf("expected ErrNoResult without metadata, got %v", err)
+	}
+}